@@ -0,0 +1,133 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler command line driver.
+package dotler
+
+import (
+	"github.com/golang/glog"
+
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+)
+
+// Signal handler!
+// a) SIGTERM/SIGINT - gracefully shuts down the crawl.
+func handleSignal(ctx context.Context, schannel chan os.Signal, cancel context.CancelFunc) {
+	for {
+		select {
+		case signl := <-schannel:
+			switch signl {
+			case syscall.SIGTERM, syscall.SIGINT:
+				cancel()
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func printStats(stats Stats) {
+	glog.Infoln("Crawl statistics")
+	glog.Infoln("===========================================")
+
+	glog.Infof("Successfully crawled URLs %d", stats.Success)
+	glog.Infof("Skipped URLs %d", stats.Skipped)
+	glog.Infof("Failed URLs %d", stats.Failed)
+	glog.Infof("Cancelled URLs %d", stats.Cancelled)
+
+	glog.Infoln("===========================================")
+}
+
+func setup(config *Config, options *Options) {
+
+	if options.NumThreads > 0 {
+		runtime.GOMAXPROCS(options.NumThreads)
+	} else {
+		runtime.GOMAXPROCS(runtime.NumCPU())
+	}
+
+	if options.ShowProg != "" {
+		glog.Infoln("Turning on gen-image")
+		options.GenImage = true
+	}
+	if options.GenImage {
+		config.GenGraph = true
+		if _, err := exec.LookPath("dot"); err != nil {
+			glog.Infoln("Need dot (from graphviz) in PATH for image generation")
+			os.Exit(2)
+		}
+	}
+}
+
+// StartCrawl is the command line driver around Crawler.
+// Basic functions such as signal processing, setup and running the crawler.
+// Persists the graph to dotler.dot, prints statistics and
+// post processes the graph as asked by options.
+// Returns the exit code.
+func StartCrawl(config Config, options Options) int {
+
+	setup(&config, &options)
+
+	procs, files, err := newOutputs(options)
+	if err != nil {
+		glog.Errorf("Invalid output format: %s", err)
+		return 2
+	}
+	defer files.Close()
+	config.Processors = procs
+
+	crawler, err := NewCrawler(config)
+	if err != nil {
+		glog.Errorf("Invalid configuration: %s", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+	go handleSignal(ctx, sigs, cancel)
+
+	result, err := crawler.Run(ctx)
+	if err != nil {
+		glog.Errorf("Crawling %s failed: %s", config.RootURL, err)
+		return 1
+	}
+
+	glog.Flush()
+
+	status := 0
+	for name, err := range result.ProcessorErrors {
+		glog.Errorf("Output %s failed: %s", name, err)
+		status = 1
+	}
+	if err = files.Close(); err != nil {
+		glog.Errorf("Failed to write outputs: %s", err)
+		status = 1
+	}
+
+	if config.GenGraph {
+		if err = ioutil.WriteFile("dotler.dot", []byte(result.Graph), 0644); err != nil {
+			glog.Errorf("Failed to write dotler.dot: %s", err)
+			status = 1
+		} else {
+			glog.Infof("We are done, phew!, persisting graph to dotler.dot\n")
+		}
+	}
+
+	printStats(result.Stats)
+
+	if options.GenImage && postProcess(result.Graph, options) != 0 {
+		return 1
+	}
+	return status
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler crawler configuration.
+package dotler
+
+import (
+	processor "github.com/ronin13/dotler/processor"
+	wire "github.com/ronin13/dotler/wire"
+
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// Config holds the settings of a single crawl.
+// DefaultConfig returns the same defaults as the command line.
+type Config struct {
+	// RootURL is the base URL to crawl from.
+	RootURL string
+	// ClientTimeout is the http timeout in seconds.
+	ClientTimeout uint
+	// CrawlThreshold is the timeout in seconds to scrape and process a single page.
+	CrawlThreshold uint
+	// MaxFetchFail is the number of failures to tolerate if http fetch fails.
+	MaxFetchFail uint
+	// Transport if not nil, makes the http requests of the crawl
+	// instead of http.DefaultTransport, for proxies or custom TLS.
+	Transport http.RoundTripper
+	// External is how links of pages to other hosts are collected and checked.
+	External ExternalOptions
+	// GenGraph turns on generation of the graphviz graph in Result.
+	GenGraph bool
+	// Graph labels and clusters the nodes of the graphviz graph.
+	Graph processor.DotOptions
+	// NodeStore is where crawled pages are kept: memory or bolt.
+	NodeStore string
+	// NodeStorePath is the path of the bolt database for NodeStore bolt.
+	NodeStorePath string
+	// Observer if not nil, is notified of crawl events.
+	Observer Observer
+	// Assets is how static assets of pages are handed to all the
+	// Processors and the graphviz graph.
+	Assets processor.AssetOptions
+	// Processors are fed every crawled page, keyed by a name used
+	// in Result.ProcessorErrors. They are used for a single Run.
+	// The name graph is taken by GenGraph.
+	Processors map[string]wire.GraphProcessor
+}
+
+// Options are the command line settings which act on the
+// Result of a crawl rather than on the crawl itself.
+type Options struct {
+	// NumThreads is the number of goroutines, defaults to NumCPU.
+	NumThreads int
+	// GenImage generates an image of the graph (implies GenGraph).
+	GenImage bool
+	// GraphFormat is the format of the generated image.
+	GraphFormat string
+	// ShowProg if not empty, is the program to display the image with.
+	ShowProg string
+	// OutputFormats is a comma separated list of OutputFormats
+	// to write as dotler.<format>.
+	OutputFormats string
+	// SitemapPriority sets priority in sitemap.xml from the depth of pages.
+	SitemapPriority bool
+	// DiagramNoAssets leaves static assets out of mermaid and plantuml.
+	DiagramNoAssets bool
+	// DiagramMaxNodes is the most nodes drawn in mermaid and plantuml, 0 for all.
+	DiagramMaxNodes int
+	// MaxClickDepth if not 0, is the click depth beyond which pages are
+	// listed in the depth report.
+	MaxClickDepth int
+	// KnownURLs if not empty, is a sitemap or a list of urls, a file or
+	// a http(s) url, to find orphan pages in the depth report.
+	KnownURLs string
+	// AuditRules is a comma separated list of processor.AuditRules
+	// checked by the audit, all if empty.
+	AuditRules string
+	// AuditSkip is a comma separated list of rules the audit leaves out.
+	AuditSkip string
+}
+
+// DefaultConfig returns a Config with the command line defaults.
+func DefaultConfig() Config {
+	return Config{
+		RootURL:        "http://www.wnohang.net/",
+		ClientTimeout:  60,
+		CrawlThreshold: 10,
+		MaxFetchFail:   2,
+		GenGraph:       true,
+		NodeStore:      "memory",
+		NodeStorePath:  "dotler.db",
+		Assets:         processor.AssetOptions{Mode: processor.AssetsFull, MinPages: 1},
+		External:       ExternalOptions{Concurrency: 4, Rate: 10},
+	}
+}
+
+// Validate checks the config for errors before crawling.
+func (config Config) Validate() error {
+	parsedURL, err := url.Parse(config.RootURL)
+	if err != nil {
+		return fmt.Errorf("Failed in parsing root url %s", err)
+	}
+	if !parsedURL.IsAbs() {
+		return fmt.Errorf("Root url %s is not absolute", config.RootURL)
+	}
+	if config.NodeStore != "memory" && config.NodeStore != "bolt" {
+		return fmt.Errorf("Unknown node store %s, need one of memory, bolt", config.NodeStore)
+	}
+	if err = config.Assets.Validate(); err != nil {
+		return err
+	}
+	if err = config.External.Validate(); err != nil {
+		return err
+	}
+	if err = config.Graph.Validate(); err != nil {
+		return err
+	}
+	if _, exists := config.Processors[graphProcessor]; exists && config.GenGraph {
+		return fmt.Errorf("Processor name %s is taken by GenGraph", graphProcessor)
+	}
+	return nil
+}
@@ -10,9 +10,14 @@ import (
 	"github.com/PuerkitoBio/purell"
 	"github.com/golang/glog"
 	wire "github.com/ronin13/dotler/wire"
+	"golang.org/x/net/html"
 
 	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -22,7 +27,7 @@ import (
 // Iterates over attributes, parses the page,
 // gets URLs from same domain, gets static assets
 // sends new links onto reqChan.
-func updateAttr(item *goquery.Selection, inPage *wire.Page, attribTypes []string, reqChan chan *wire.Page, nodes wire.NodeMapper) error {
+func (crawler *Crawler) updateAttr(item *goquery.Selection, inPage *wire.Page, attribTypes []string, reqChan chan *wire.Page, nodes wire.NodeMapper) error {
 
 	var nPage *wire.Page
 	var err error
@@ -38,20 +43,13 @@ func updateAttr(item *goquery.Selection, inPage *wire.Page, attribTypes []string
 				return nil
 			}
 			// Normalizing links.
-			link, err = purell.NormalizeURLString(link, purell.FlagsUsuallySafeGreedy)
-
+			parsedURL, err = normalizeLink(base, link)
 			if err != nil {
 				glog.Infof("Failed to normalize %s with error %s", link, err)
 				return err
 			}
-			parsedURL, err = url.Parse(link)
-			if err != nil {
-				glog.Infof("Failed to parse %s with error %s", link, err)
-				return err
-			}
-			if !parsedURL.IsAbs() {
-				parsedURL = base.ResolveReference(parsedURL)
-			}
+			fragment := parsedURL.Fragment
+			rawQuery := parsedURL.RawQuery
 			parsedURL.RawQuery = ""
 			parsedURL.Fragment = ""
 			if isStatic(parsedURL.String()) {
@@ -65,19 +63,29 @@ func updateAttr(item *goquery.Selection, inPage *wire.Page, attribTypes []string
 			} else if parsedURL.Host == base.Host {
 
 				nPage = nodes.Exists(parsedURL.String())
+				link := linkContext(item)
+				link.Fragment = fragment
 
 				// Already processed
 				if nPage != nil {
-					updateOutLinksWithCard(parsedURL.String(), inPage, nPage)
+					updateOutLinksWithCard(parsedURL.String(), inPage, nPage, link)
 				} else {
 					// New discovery!
 
 					// Title not known at this point
-					nPage = &wire.Page{PageURL: parsedURL}
+					nPage = &wire.Page{PageURL: parsedURL, Depth: inPage.Depth + 1}
 
 					//TODO: go writeToChan?
-					writeToChan(nPage, reqChan)
-					updateOutLinksWithCard(parsedURL.String(), inPage, nPage)
+					crawler.enqueue(nPage, reqChan)
+					updateOutLinksWithCard(parsedURL.String(), inPage, nPage, link)
+				}
+				crawler.observer.LinkDiscovered(inPage, nPage)
+			} else if external := crawler.config.External; (external.Collect || external.Check) && item.Is("a") && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") {
+				// Never crawled, the query is kept as it is a different page.
+				parsedURL.RawQuery = rawQuery
+				inPage.External[parsedURL.String()]++
+				if crawler.checker != nil {
+					crawler.checker.check(parsedURL.String())
 				}
 			} else {
 				// Very verbose!
@@ -90,13 +98,164 @@ func updateAttr(item *goquery.Selection, inPage *wire.Page, attribTypes []string
 	return nil
 }
 
-func updateOutLinksWithCard(key string, iPage, nPage *wire.Page) {
+// Resolves link against base before normalizing it, as normalizing
+// a relative link like / or ../ leaves nothing of it.
+// The root path is kept as /.
+func normalizeLink(base *url.URL, link string) (*url.URL, error) {
+	parsedURL, err := base.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	link, err = purell.NormalizeURLString(parsedURL.String(), purell.FlagsUsuallySafeGreedy)
+	if err != nil {
+		return nil, err
+	}
+	parsedURL, err = url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	if parsedURL.Path == "" {
+		parsedURL.Path = "/"
+	}
+	return parsedURL, nil
+}
+
+func updateOutLinksWithCard(key string, iPage, nPage *wire.Page, link wire.Link) {
 
 	if _, exists := iPage.OutLinks[key]; exists {
 		iPage.OutLinks[key].Card++
 	} else {
 		iPage.OutLinks[key] = &wire.PageWithCard{Page: nPage, Card: 1}
 	}
+	iPage.OutLinks[key].Links = append(iPage.OutLinks[key].Links, link)
+}
+
+// ARIA roles of the landmark elements.
+var landmarkRoles = map[string]string{
+	"navigation":    "nav",
+	"banner":        "header",
+	"contentinfo":   "footer",
+	"main":          "main",
+	"complementary": "aside",
+}
+
+// Returns the anchor text, title, rel and page region of the link in item.
+func linkContext(item *goquery.Selection) wire.Link {
+	var link wire.Link
+	if item.Is("a") {
+		link.Text = strings.Join(strings.Fields(item.Text()), " ")
+		if link.Text == "" {
+			link.Text, _ = item.Find("img[alt]").First().Attr("alt")
+			link.Text = strings.TrimSpace(link.Text)
+		}
+	}
+	link.Title, _ = item.Attr("title")
+	link.Title = strings.TrimSpace(link.Title)
+	if rel, exists := item.Attr("rel"); exists {
+		link.Rel = strings.Fields(strings.ToLower(rel))
+	}
+
+	// The nearest landmark element or role.
+	item.Parents().EachWithBreak(func(i int, parent *goquery.Selection) bool {
+		if role, exists := parent.Attr("role"); exists && landmarkRoles[role] != "" {
+			link.Region = landmarkRoles[role]
+			return false
+		}
+		for _, region := range landmarkRoles {
+			if parent.Is(region) {
+				link.Region = region
+				return false
+			}
+		}
+		return true
+	})
+	return link
+}
+
+// Fills in the title and metadata of inPage from doc.
+func updateMeta(doc *goquery.Document, inPage *wire.Page) {
+	inPage.Title = strings.TrimSpace(doc.Find("title").First().Text())
+	doc.Find("meta").Each(func(i int, item *goquery.Selection) {
+		name, _ := item.Attr("name")
+		content, _ := item.Attr("content")
+		switch {
+		case strings.EqualFold(name, "description") && inPage.Description == "":
+			inPage.Description = strings.TrimSpace(content)
+		case strings.EqualFold(name, "robots") && hasNoIndex(content):
+			inPage.NoIndex = true
+		}
+	})
+	doc.Find("link[href]").EachWithBreak(func(i int, item *goquery.Selection) bool {
+		rel, _ := item.Attr("rel")
+		for _, value := range strings.Fields(strings.ToLower(rel)) {
+			if value != "canonical" {
+				continue
+			}
+			href, _ := item.Attr("href")
+			if canonical, err := normalizeLink(inPage.PageURL, strings.TrimSpace(href)); err == nil {
+				inPage.Canonical = canonical.String()
+			}
+			return false
+		}
+		return true
+	})
+	anchors := make(map[string]bool)
+	doc.Find("[id], a[name]").Each(func(i int, item *goquery.Selection) {
+		for _, attr := range []string{"id", "name"} {
+			if anchor, _ := item.Attr(attr); anchor != "" && (attr == "id" || item.Is("a")) {
+				anchors[anchor] = true
+			}
+		}
+	})
+	inPage.Anchors = nil
+	for anchor := range anchors {
+		inPage.Anchors = append(inPage.Anchors, anchor)
+	}
+	sort.Strings(inPage.Anchors)
+	inPage.H1 = strings.Join(strings.Fields(doc.Find("h1").First().Text()), " ")
+	inPage.H1Count = doc.Find("h1").Length()
+	inPage.MissingAlt = doc.Find("img").FilterFunction(func(i int, item *goquery.Selection) bool {
+		_, hasAlt := item.Attr("alt")
+		return !hasAlt
+	}).Length()
+	lang, _ := doc.Find("html").First().Attr("lang")
+	inPage.Lang = strings.TrimSpace(lang)
+	var words []string
+	for _, body := range doc.Find("body").Nodes {
+		words = visibleWords(body, words)
+	}
+	inPage.WordCount = len(words)
+	inPage.SimHash = simHash(words)
+}
+
+// If robots directives, of <meta name="robots"> or X-Robots-Tag, have noindex.
+// Directives of X-Robots-Tag may be for a user agent, "googlebot: noindex".
+func hasNoIndex(directives string) bool {
+	for _, directive := range strings.Split(strings.ToLower(directives), ",") {
+		directive = strings.TrimSpace(directive[strings.LastIndex(directive, ":")+1:])
+		if directive == "noindex" || directive == "none" {
+			return true
+		}
+	}
+	return false
+}
+
+// Appends the words in the text under node to words,
+// leaving out scripts and styles.
+func visibleWords(node *html.Node, words []string) []string {
+	if node.Type == html.TextNode {
+		return append(words, strings.Fields(node.Data)...)
+	}
+	if node.Type == html.ElementNode {
+		switch node.Data {
+		case "script", "style", "noscript", "template":
+			return words
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		words = visibleWords(child, words)
+	}
+	return words
 }
 
 // Get all links from a html page
@@ -104,28 +263,41 @@ func updateOutLinksWithCard(key string, iPage, nPage *wire.Page) {
 // For attributes: href and src
 // Updates Page structure with static and outside links.
 // Uses goquery for parsing.
-func getAllLinks(cancelParse context.Context, inPage *wire.Page, reqChan chan *wire.Page, nodes wire.NodeMapper) chan bool {
+func (crawler *Crawler) getAllLinks(cancelParse context.Context, inPage *wire.Page, reqChan chan *wire.Page, nodes wire.NodeMapper) chan bool {
 
 	doneChan := make(chan bool, 1)
 
 	go func() {
 		// getContent has a timeout - clientTimeout
-		body, err := getContent(inPage.PageURL)
+		body, resp, err := crawler.getContent(inPage.PageURL)
 		if err != nil {
 			glog.Infof("Failed to crawl %s", inPage.PageURL.String())
 			inPage.FailCount++
-			if inPage.FailCount <= maxFetchFail {
+			if inPage.FailCount <= crawler.config.MaxFetchFail {
 				//TODO: go writeToChan?
-				writeToChan(inPage, reqChan)
+				crawler.enqueue(inPage, reqChan)
 			}
 			doneChan <- false
 			return
 		}
 
+		inPage.Status = resp.StatusCode
+		inPage.ContentHash = fmt.Sprintf("%x", sha256.Sum256([]byte(body)))
+		inPage.Headers = securityHeaders(resp.Header)
+		inPage.Cookies = cookieFlags(resp)
+		for _, robots := range resp.Header["X-Robots-Tag"] {
+			inPage.NoIndex = inPage.NoIndex || hasNoIndex(robots)
+		}
+		if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			inPage.LastModified = lastModified
+		}
 		inPage.OutLinks = make(map[string]*wire.PageWithCard)
+		inPage.External = make(map[string]uint)
 		inPage.StatList = make(map[string]wire.StatPage)
 		doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
 		panicCrawl(err)
+		updateMeta(doc, inPage)
+		updateInsecure(doc, inPage)
 
 		successful := true
 
@@ -136,7 +308,7 @@ func getAllLinks(cancelParse context.Context, inPage *wire.Page, reqChan chan *w
 				successful = false
 				return false
 			default:
-				err = updateAttr(item, inPage, []string{"href", "src"}, reqChan, nodes)
+				err = crawler.updateAttr(item, inPage, []string{"href", "src"}, reqChan, nodes)
 				if err != nil {
 					glog.Infof("Skipping this - %s - page, probably bad", inPage.PageURL.String())
 					successful = false
@@ -153,17 +325,18 @@ func getAllLinks(cancelParse context.Context, inPage *wire.Page, reqChan chan *w
 
 }
 
-// Crawl is the core crawl function called from main.
+// Crawl is the core crawl function called from Run.
 // Uses getAllLinks for actual processing.
 // updNodeMap to ensure nodes processed successfully are not revisited.
 // Gets two channels - reqChan and respChan.
 // Sends reqChan downwards for further parse + load.
-// Uses respChan for graph rendering.
+// Uses respChan, if not nil, for graph rendering.
 // Also has a timeout of crawlThreshold.
 // Uses a new child context noParse - used to terminate parsing.
-func Crawl(cancelCrawl context.Context, inPage *wire.Page, reqChan chan *wire.Page, respChan chan *wire.Page, waiter *sync.WaitGroup, nodes wire.NodeMapper) {
+func (crawler *Crawler) Crawl(cancelCrawl context.Context, inPage *wire.Page, reqChan chan *wire.Page, respChan chan *wire.Page, waiter *sync.WaitGroup, nodes wire.NodeMapper) {
 
 	defer waiter.Done()
+	defer crawler.dequeue()
 	if err := nodes.Add(inPage.PageURL.String(), inPage); err != nil {
 		if glog.V(2) {
 			glog.Errorf("Possible duplicate addition %s", inPage.PageURL.String())
@@ -174,35 +347,43 @@ func Crawl(cancelCrawl context.Context, inPage *wire.Page, reqChan chan *wire.Pa
 	glog.Infof("Processing page %s", inPage.PageURL.String())
 
 	noParse, terminate := context.WithCancel(cancelCrawl)
-	doneChan := getAllLinks(noParse, inPage, reqChan, nodes)
+	doneChan := crawler.getAllLinks(noParse, inPage, reqChan, nodes)
 
 	for {
 		select {
 		case <-cancelCrawl.Done():
 			terminate()
-			atomic.AddUint64(&crawlCancelled, 1)
+			atomic.AddUint64(&crawler.stats.Cancelled, 1)
+			crawler.observer.PageDropped(inPage, Cancelled)
 			glog.Infof("Cancelling crawling the page %s", inPage.PageURL.String())
 			return
 		case rval := <-doneChan:
 			terminate()
 			if rval == false {
-				atomic.AddUint64(&crawlFail, 1)
+				atomic.AddUint64(&crawler.stats.Failed, 1)
+				crawler.observer.PageDropped(inPage, Failed)
 				glog.Infof("Failed to crawl %s", inPage.PageURL.String())
 				return
 			}
 
-			atomic.AddUint64(&crawlSuccess, 1)
+			atomic.AddUint64(&crawler.stats.Success, 1)
+			crawler.observer.PageFetched(inPage)
 			glog.Infof("Successfully crawled %s", inPage.PageURL.String())
 
-			if genGraph {
+			if respChan != nil {
 				//TODO: go writeToChan?
 				writeToChan(inPage, respChan)
+			} else if spill, ok := nodes.(wire.Spiller); ok {
+				if err := spill.Spill(inPage.PageURL.String()); err != nil {
+					glog.Errorf("Failed to spill %s: %s", inPage.PageURL.String(), err)
+				}
 			}
 			return
-		case <-time.After(time.Second * time.Duration(crawlThreshold)):
-			atomic.AddUint64(&crawlSkipped, 1)
+		case <-time.After(time.Second * time.Duration(crawler.config.CrawlThreshold)):
+			atomic.AddUint64(&crawler.stats.Skipped, 1)
+			crawler.observer.PageDropped(inPage, Skipped)
 			terminate()
-			glog.Infof("This page %s is taking too long (> %d), skipping it!", inPage.PageURL.String(), crawlThreshold)
+			glog.Infof("This page %s is taking too long (> %d), skipping it!", inPage.PageURL.String(), crawler.config.CrawlThreshold)
 			return
 		}
 	}
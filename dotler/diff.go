@@ -0,0 +1,75 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler diff subcommand.
+package dotler
+
+import (
+	processor "github.com/ronin13/dotler/processor"
+
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DiffCommand is the diff subcommand, which reports the pages, links,
+// statuses and static assets that changed between two crawls saved as
+// JSON or dotler.dot. Like diff(1), returns 0 if nothing changed,
+// 1 if something did and 2 on errors.
+// Usage of ./dotler diff [flags] old.json new.json:
+//
+//	-dot string
+//	      If not empty, file to write the graph of both crawls to, added in green and removed in red
+//	-format string
+//	      If not empty, format of an image of the graph of both crawls, svg etc.
+func DiffCommand(args []string, stdout io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	dotFile := flags.String("dot", "", "If not empty, file to write the graph of both crawls to, added in green and removed in red")
+	format := flags.String("format", "", "If not empty, format of an image of the graph of both crawls, svg etc.")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Usage: dotler diff [-dot file] [-format svg] old.json|old.dot new.json|new.dot")
+		return 2
+	}
+
+	oldDoc, err := loadCrawl(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	newDoc, err := loadCrawl(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	diff := processor.Diff(oldDoc, newDoc)
+	if err = diff.WriteReport(stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *dotFile != "" {
+		var graph bytes.Buffer
+		if err = diff.WriteDot(&graph, oldDoc, newDoc); err == nil {
+			err = ioutil.WriteFile(*dotFile, graph.Bytes(), 0644)
+		}
+		if err == nil && *format != "" {
+			err = renderGraph(graph.String(), *format, strings.TrimSuffix(*dotFile, ".dot")+"."+*format)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %s\n", *dotFile, err)
+			return 2
+		}
+	}
+	if diff.Empty() {
+		return 0
+	}
+	return 1
+}
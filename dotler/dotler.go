@@ -9,17 +9,12 @@ import (
 	processor "github.com/ronin13/dotler/processor"
 	wire "github.com/ronin13/dotler/wire"
 
+	"bytes"
 	"context"
-	"fmt"
-	"io/ioutil"
+	"net/http"
 	"net/url"
-	"os"
-	"os/exec"
-	"os/signal"
-	"runtime"
 	"sync"
 	"sync/atomic"
-	"syscall"
 	"time"
 )
 
@@ -29,203 +24,188 @@ const (
 	// STATICTYPES defines extensions we consider to be 'static' when processing a page.
 	// Also add rss|xml|atom
 	STATICTYPES = `\.(jpg|gif|bmp|jpeg|png|svg|mp3|mp4|flv|js|css|webm|ogg|flac|wav|ico|atom|rss|xml)$`
-)
 
-var (
-	// RootURL is the base URL to crawl from.
-	RootURL     string
-	genImage    bool
-	genGraph    bool
-	numThreads  int
-	graphFormat string
-	showProg    string
-
-	// ClientTimeout is the http timeout.
-	ClientTimeout    uint
-	crawlThreshold   uint
-	domain           string
-	termChannel      chan struct{}
-	reqChan, dotChan chan *wire.Page
-	maxFetchFail     uint
-	crawlSuccess     uint64
-	crawlFail        uint64
-	crawlSkipped     uint64
-	crawlCancelled   uint64
+	// Name of the processor generating Result.Graph.
+	graphProcessor = "graph"
 )
 
-// Signal handler!
-// a) SIGTERM/SIGINT - gracefully shuts down the server.
-func handleSignal(schannel chan os.Signal) {
-	for {
-		signl := <-schannel
-		switch signl {
-		case syscall.SIGTERM, syscall.SIGINT:
-			termChannel <- struct{}{}
-			glog.Infoln("Time to leave and cleanup!")
-			return
-		}
-	}
+// Stats are the crawl statistics of a run.
+type Stats = wire.Stats
+
+// Result is what a Crawler returns after a run.
+// Graph is the graphviz graph, empty unless Config.GenGraph is set.
+// ProcessorErrors has the errors of failed Config.Processors by name.
+// External has the checks of links to other hosts by url, nil unless
+// Config.External.Check is set.
+type Result struct {
+	RootURL         string
+	Graph           string
+	Stats           Stats
+	StartTime       time.Time
+	EndTime         time.Time
+	ProcessorErrors map[string]error
+	External        map[string]wire.LinkCheck
 }
 
-func printStats() {
-	glog.Infoln("Crawl statistics")
-	glog.Infoln("===========================================")
-	var statsFinal uint64
-
-	statsFinal = atomic.LoadUint64(&crawlSuccess)
-	glog.Infof("Successfully crawled URLs %d", statsFinal)
-
-	statsFinal = atomic.LoadUint64(&crawlSkipped)
-	glog.Infof("Skipped URLs %d", statsFinal)
-
-	statsFinal = atomic.LoadUint64(&crawlFail)
-	glog.Infof("Failed URLs %d", statsFinal)
-
-	statsFinal = atomic.LoadUint64(&crawlCancelled)
-	glog.Infof("Cancelled URLs %d", statsFinal)
+// Crawler crawls a single site as described by its Config.
+// Run must not be called concurrently on the same Crawler,
+// separate Crawlers can run in parallel.
+type Crawler struct {
+	// Kept first for 64-bit alignment of atomic counters.
+	stats Stats
+	// Pages sent to reqChan whose Crawl has not returned yet.
+	pending  int64
+	idle     chan struct{}
+	config   Config
+	client   *http.Client
+	observer Observer
+	// Checks external links of a run, nil unless Config.External.Check.
+	checker *linkChecker
+}
 
-	glog.Infoln("===========================================")
+// NewCrawler returns a new Crawler after validating config.
+func NewCrawler(config Config) (*Crawler, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	crawler := &Crawler{
+		config:   config,
+		observer: config.Observer,
+		client: &http.Client{
+			Timeout:   time.Duration(config.ClientTimeout) * time.Second,
+			Transport: config.Transport,
+		},
+	}
+	if crawler.observer == nil {
+		crawler.observer = BaseObserver{}
+	}
+	return crawler, nil
 }
 
-func setup() {
+// Config returns the configuration of the crawler.
+func (crawler *Crawler) Config() Config {
+	return crawler.config
+}
 
-	if numThreads > 0 {
-		runtime.GOMAXPROCS(numThreads)
-	} else {
-		runtime.GOMAXPROCS(runtime.NumCPU())
+func (crawler *Crawler) snapshotStats() Stats {
+	return Stats{
+		Success:   atomic.LoadUint64(&crawler.stats.Success),
+		Skipped:   atomic.LoadUint64(&crawler.stats.Skipped),
+		Failed:    atomic.LoadUint64(&crawler.stats.Failed),
+		Cancelled: atomic.LoadUint64(&crawler.stats.Cancelled),
 	}
+}
 
-	if showProg != "" {
-		glog.Infoln("Turning on gen-image")
-		genImage = true
-	}
-	if genImage {
-		genGraph = true
-		if _, err := exec.LookPath("dot"); err != nil {
-			glog.Infoln("Need dot (from graphviz) in PATH for image generation")
-			os.Exit(2)
-		}
+// Returns the NodeMapper selected with Config.NodeStore.
+func (crawler *Crawler) newNodeMapper(ctx context.Context) (wire.NodeMapper, context.CancelFunc, error) {
+	if crawler.config.NodeStore == "bolt" {
+		glog.Infof("Spilling crawled pages to %s", crawler.config.NodeStorePath)
+		return wire.NewBoltMapper(crawler.config.NodeStorePath)
 	}
+	nodeMap, cFunc := wire.NewNodeMapper(ctx)
+	return nodeMap, cFunc, nil
 }
 
-// StartCrawl is the main function with deferred processing in case of return with code.
-// Basic functions such as signal processing. setup and main loop.
-// Exits during shutdown or when idle time is reached, and then waits.
+// Run crawls from Config.RootURL till all the urls in the domain
+// have been crawled or ctx is cancelled, whichever is first.
 // The main loop queries the reqChan and dispatches crawl function repeatedly.
-func StartCrawl(startURL string) int {
-	var err error
-	var parsedURL *url.URL
-	var endTime int64
-	var once sync.Once
+// Waits for all crawl goroutines before returning the Result.
+func (crawler *Crawler) Run(ctx context.Context) (*Result, error) {
 	var wg sync.WaitGroup
-	var nodeMap wire.NodeMapper
-
-	var printerChan wire.GraphProcessor
-
-	crawlDone := make(chan struct{}, 2)
-	reqChan = make(chan *wire.Page, MAXWORKERS)
-	termChannel = make(chan struct{}, 2)
-
-	setup()
+	var fanOut *processor.FanOut
+	var dotChan chan *wire.Page
+	var graph bytes.Buffer
 
-	parentContext := context.Background()
-	noCrawl, terminate := context.WithCancel(parentContext)
+	parsedURL, err := url.Parse(crawler.config.RootURL)
+	if err != nil {
+		return nil, err
+	}
+	// Same as the links to it.
+	if parsedURL, err = normalizeLink(parsedURL, ""); err != nil {
+		return nil, err
+	}
 
-	nodeMap, cFunc := wire.NewNodeMapper(parentContext)
+	nodeMap, cFunc, err := crawler.newNodeMapper(ctx)
+	if err != nil {
+		return nil, err
+	}
 	defer cFunc()
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	crawler.stats = Stats{}
+	crawler.pending = 0
+	crawler.idle = make(chan struct{}, 1)
+	reqChan := make(chan *wire.Page, MAXWORKERS)
+	noCrawl, terminate := context.WithCancel(ctx)
+	defer terminate()
+
+	crawler.checker = nil
+	if crawler.config.External.Check {
+		// Not noCrawl, the checks go on after the last page.
+		crawler.checker = newLinkChecker(ctx, &http.Client{
+			Timeout:   crawler.client.Timeout,
+			Transport: crawler.config.Transport,
+		}, crawler.config.External)
+	}
 
-	defer wg.Wait()
+	crawler.enqueue(&wire.Page{PageURL: parsedURL}, reqChan)
 
-	parsedURL, err = url.Parse(startURL)
-	if err != nil {
-		panic(fmt.Sprintf("Failed in parsing root url %s", err))
+	procs := make(map[string]wire.GraphProcessor)
+	for name, proc := range crawler.config.Processors {
+		procs[name] = proc
 	}
-	reqChan <- &wire.Page{PageURL: parsedURL}
-
-	if genGraph {
+	if crawler.config.GenGraph {
+		procs[graphProcessor] = processor.NewDotPrinter(&graph, crawler.config.Graph)
+	}
+	if len(procs) > 0 {
+		spill, _ := nodeMap.(wire.Spiller)
 		dotChan = make(chan *wire.Page, MAXWORKERS)
-		printerChan = processor.NewPrinter()
-		printerChan.ProcessLoop(noCrawl, dotChan)
+		fanOut = processor.NewFanOut(procs, spill, crawler.config.Assets)
+		fanOut.ProcessLoop(dotChan)
 	}
-	go handleSignal(sigs)
-
-	waitChan := make(chan struct{})
-	go func() {
-		for {
-			<-waitChan
-			wg.Wait()
-			if len(reqChan) > 0 {
-				continue
-			} else {
-				crawlDone <- struct{}{}
-				return
-			}
-		}
-	}()
-
-	startTime := time.Now().Unix()
-	glog.Infof("Starting crawl for %s at %s", startURL, time.Now().String())
-
-	extStatus := make(chan int, 1)
-
-	go func() {
-		<-termChannel
-		var dotString string
-
-		status := 0
-
-		if crawlDone != nil {
-			crawlDone <- struct{}{}
-		}
-		terminate()
-		if genGraph {
-			dotString = <-printerChan.Result()
-			close(dotChan)
-		}
-		// This is safe.
-		wg.Wait()
-
-		glog.Flush()
 
-		if genGraph {
-			err = ioutil.WriteFile("dotler.dot", []byte(dotString), 0644)
-			panicCrawl(err)
-			glog.Infof("We are done, phew!, persisting graph to dotler.dot\n")
-		}
-
-		printStats()
-
-		if genImage {
-			status = postProcess(dotString)
-		}
-		extStatus <- status
-
-	}()
+	result := &Result{RootURL: crawler.config.RootURL, StartTime: time.Now()}
+	glog.Infof("Starting crawl for %s at %s", crawler.config.RootURL, result.StartTime.String())
 
+crawlLoop:
 	for {
 		select {
 		case inPage := <-reqChan:
 			if inPage != nil {
 				wg.Add(1)
-				go Crawl(noCrawl, inPage, reqChan, dotChan, &wg, nodeMap)
-				once.Do(func() { close(waitChan) })
+				go crawler.Crawl(noCrawl, inPage, reqChan, dotChan, &wg, nodeMap)
 			}
-		case <-crawlDone:
-			endTime = time.Now().Unix()
-			glog.Infof("Crawling %s took %d seconds", startURL, endTime-startTime)
-			reqChan = nil
-			crawlDone = nil
-			termChannel <- struct{}{}
-
-		}
-
-		if reqChan == nil && crawlDone == nil {
-			break
+		case <-crawler.idle:
+			break crawlLoop
+		case <-ctx.Done():
+			glog.Infoln("Time to leave and cleanup!")
+			break crawlLoop
 		}
 	}
-	return <-extStatus
 
+	result.EndTime = time.Now()
+	glog.Infof("Crawling %s took %d seconds", crawler.config.RootURL, result.EndTime.Unix()-result.StartTime.Unix())
+
+	terminate()
+	// This is safe.
+	wg.Wait()
+
+	result.Stats = crawler.snapshotStats()
+	if crawler.checker != nil {
+		glog.Infoln("Waiting for checks of external links")
+		result.External = crawler.checker.wait()
+	}
+	if fanOut != nil {
+		// No crawls are left to write to it.
+		close(dotChan)
+		result.ProcessorErrors = fanOut.Finish(&wire.CrawlInfo{
+			RootURL:   parsedURL.String(),
+			StartTime: result.StartTime,
+			EndTime:   result.EndTime,
+			Stats:     result.Stats,
+			External:  result.External,
+		})
+		result.Graph = graph.String()
+	}
+	crawler.observer.CrawlFinished(result)
+	return result, nil
 }
@@ -0,0 +1,162 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler checks of links to other hosts.
+package dotler
+
+import (
+	"github.com/golang/glog"
+	wire "github.com/ronin13/dotler/wire"
+
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ExternalOptions is how links of pages to other hosts are handled,
+// they are never crawled.
+type ExternalOptions struct {
+	// Collect keeps the links of pages to other hosts in wire.Page.External.
+	Collect bool
+	// Check requests every collected link once, with HEAD, or GET if the
+	// host answers HEAD with a 4xx, into wire.CrawlInfo.External. Implies Collect.
+	Check bool
+	// Concurrency is the most checks at once, apart from the crawl.
+	Concurrency int
+	// Rate if not 0, is the most checks started per second,
+	// up to one a nanosecond.
+	Rate float64
+}
+
+// Validate checks options for errors.
+func (options ExternalOptions) Validate() error {
+	if options.Check && options.Concurrency < 1 {
+		return fmt.Errorf("External link check concurrency %d is below 1", options.Concurrency)
+	}
+	if options.Rate < 0 {
+		return fmt.Errorf("Negative external link check rate %g", options.Rate)
+	}
+	if options.Rate > 0 && time.Duration(float64(time.Second)/options.Rate) <= 0 {
+		return fmt.Errorf("External link check rate %g is above %d per second", options.Rate, time.Second)
+	}
+	return nil
+}
+
+// linkChecker checks external links in the background while the
+// crawl goes on, every link once.
+type linkChecker struct {
+	ctx    context.Context
+	client *http.Client
+	// Taken for every request, Concurrency of them.
+	slots  chan struct{}
+	ticker *time.Ticker
+	waiter sync.WaitGroup
+	sync.Mutex
+	// Checks not done yet are zero.
+	results map[string]wire.LinkCheck
+}
+
+// Returns a linkChecker making requests with client
+// till ctx is done, limited as in options.
+func newLinkChecker(ctx context.Context, client *http.Client, options ExternalOptions) *linkChecker {
+	checker := &linkChecker{
+		ctx:     ctx,
+		client:  client,
+		slots:   make(chan struct{}, options.Concurrency),
+		results: make(map[string]wire.LinkCheck),
+	}
+	if options.Rate > 0 {
+		checker.ticker = time.NewTicker(time.Duration(float64(time.Second) / options.Rate))
+	}
+	return checker
+}
+
+// Checks link, unless it already is.
+func (checker *linkChecker) check(link string) {
+	checker.Lock()
+	_, exists := checker.results[link]
+	checker.results[link] = wire.LinkCheck{}
+	checker.Unlock()
+	if exists {
+		return
+	}
+
+	checker.waiter.Add(1)
+	go func() {
+		defer checker.waiter.Done()
+		select {
+		case checker.slots <- struct{}{}:
+			defer func() { <-checker.slots }()
+		case <-checker.ctx.Done():
+			return
+		}
+		if checker.ticker != nil {
+			select {
+			case <-checker.ticker.C:
+			case <-checker.ctx.Done():
+				return
+			}
+		}
+		result := checker.request(link)
+		if checker.ctx.Err() != nil {
+			return
+		}
+		if glog.V(2) {
+			glog.Infof("Checked %s: %+v", link, result)
+		}
+		checker.Lock()
+		checker.results[link] = result
+		checker.Unlock()
+	}()
+}
+
+func (checker *linkChecker) request(link string) wire.LinkCheck {
+	// Hosts refuse HEAD with all sorts of 4xx, 403, 404, 405..
+	// so those are confirmed with GET.
+	resp, err := checker.do(http.MethodHead, link)
+	if err == nil && resp.StatusCode >= 400 && resp.StatusCode <= 499 {
+		resp, err = checker.do(http.MethodGet, link)
+	}
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return wire.LinkCheck{Error: err.Error()}
+	}
+	return wire.LinkCheck{Status: resp.StatusCode}
+}
+
+// Returns the response to a request, its body closed unread.
+func (checker *linkChecker) do(method, link string) (*http.Response, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := checker.client.Do(req.WithContext(checker.ctx))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// Waits for the checks and returns them by link,
+// without those left undone when ctx was done.
+func (checker *linkChecker) wait() map[string]wire.LinkCheck {
+	checker.waiter.Wait()
+	if checker.ticker != nil {
+		checker.ticker.Stop()
+	}
+	checker.Lock()
+	defer checker.Unlock()
+	results := make(map[string]wire.LinkCheck, len(checker.results))
+	for link, result := range checker.results {
+		if result.Status != 0 || result.Error != "" {
+			results[link] = result
+		}
+	}
+	return results
+}
@@ -5,13 +5,36 @@
 package dotler
 
 import (
+	processor "github.com/ronin13/dotler/processor"
+
 	"flag"
+	"strings"
 )
 
-// ParseFlags provides parsing of all the flags.
+// ParseFlags provides parsing of all the flags into a Config and Options.
 // Usage of ./dotler:
 //  -alsologtostderr
 //        log to standard error as well as files
+//  -assets string
+//        Static assets in all outputs: full, none, aggregate, shared-only (default "full")
+//  -assets-min-pages int
+//        Pages an asset must be referenced by, more than, for -assets=shared-only (default 1)
+//  -audit-rules string
+//        Comma separated rules of the audit, all if empty: missing-title, duplicate-title, long-title, missing-description, duplicate-description, missing-h1, multiple-h1, image-alt, missing-canonical, linked-noindex
+//  -audit-skip string
+//        Comma separated rules the audit leaves out
+//  -diagram-max-nodes int
+//        Most nodes drawn in mermaid and plantuml, rest are collapsed, 0 for all (default 100)
+//  -diagram-no-assets
+//        Leave static assets out of mermaid and plantuml
+//  -external
+//        Collect links to other hosts, never crawled, for the external report and graph
+//  -external-check
+//        Check links to other hosts with HEAD or GET (implies external)
+//  -external-concurrency int
+//        Most checks of links to other hosts at once (default 4)
+//  -external-rate float
+//        Most checks of links to other hosts started per second, 0 for no limit (default 10)
 //  -format string
 //        Format of generated image (default "svg")
 //  -gen-graph
@@ -20,18 +43,46 @@ import (
 //        Generate an image of sitemap (implies gen-graph)
 //  -display-prog string
 //        If not empty, program to show the image (implies gen-graph and gen-image), chromium etc.
+//  -graph-cluster-depth int
+//        Group nodes of the graph into clusters by up to this many path segments, 0 for none
+//  -graph-anchor-labels
+//        Label links of the graph with their most common anchor text instead of their count
+//  -graph-collapse
+//        Draw the innermost clusters of the graph as a single node with their page count
+//  -graph-duplicates
+//        Outline pages of the graph with the same content in the same color
+//  -graph-insecure
+//        Color labels of https pages of the graph with http:// references, red for active mixed content
+//  -graph-label string
+//        Page field shown as label of the nodes of the graph: url, title, h1, description
+//  -graph-rank
+//        Size and color pages of the graph by their PageRank
+//  -graph-tooltip string
+//        Page field shown as tooltip of the nodes of the graph: url, title, h1, description
+//  -known-urls string
+//        Sitemap or list of urls, file or http(s) url, to find orphan pages in the depth report
 //  -log_backtrace_at value
 //        when logging hits line file:N, emit a stack trace
 //  -log_dir string
 //        If non-empty, write log files in this directory
 //  -logtostderr
 //        log to standard error instead of files
+//  -max-click-depth int
+//        Click depth beyond which pages are listed in the depth report, 0 for none (default 3)
 //  -max-crawl uint
 //        Timeout in seconds to scrape and process a single page (default 10)
 //  -max-threads int
 //        Number of goroutines, defaults to NumCPU
+//  -node-store string
+//        Where crawled pages are kept: memory or bolt (default "memory")
+//  -node-store-path string
+//        Path of the bolt database for -node-store=bolt (default "dotler.db")
+//  -output-format string
+//        Comma separated formats to write as dotler.<format> besides dotler.dot: json, graphml, gexf, csv, sitemap, mermaid, plantuml, html, rank, depth, duplicates, audit-json, audit-csv, fragments, mixed, headers, external
 //  -retry uint
 //        Number of failures to tolerate if http fetch fails (default 2)
+//  -sitemap-priority
+//        Set priority in sitemap.xml from the depth of pages
 //  -stderrthreshold value
 //        logs at or above this threshold go to stderr
 //  -timeout uint
@@ -42,18 +93,51 @@ import (
 //        log level for V logs
 //  -vmodule value
 //      comma-separated list of pattern=N settings for file-filtered logging
-func ParseFlags() {
-	flag.StringVar(&RootURL, "url", "http://www.wnohang.net/", "Url to crawl")
-	flag.UintVar(&ClientTimeout, "timeout", 60, "Timeout in seconds")
-	flag.UintVar(&maxFetchFail, "retry", 2, "Number of failures to tolerate if http fetch fails")
-	flag.UintVar(&crawlThreshold, "max-crawl", 10, "Timeout in seconds to scrape and process a single page")
-	flag.IntVar(&numThreads, "max-threads", 0, "Number of goroutines, defaults to NumCPU")
-
-	flag.BoolVar(&genImage, "gen-image", false, "Generate an image of sitemap (implies gen-graph), default false")
-	flag.BoolVar(&genGraph, "gen-graph", true, "Generate a graphviz graph")
-	flag.StringVar(&showProg, "display-prog", "", "If not empty, program to display the image (implies gen-graph and gen-image), chromium etc.")
-	flag.StringVar(&graphFormat, "format", "svg", "Format of generated image")
+func ParseFlags() (Config, Options) {
+	config := DefaultConfig()
+	var options Options
+
+	flag.StringVar(&config.RootURL, "url", config.RootURL, "Url to crawl")
+	flag.UintVar(&config.ClientTimeout, "timeout", config.ClientTimeout, "Timeout in seconds")
+	flag.UintVar(&config.MaxFetchFail, "retry", config.MaxFetchFail, "Number of failures to tolerate if http fetch fails")
+	flag.UintVar(&config.CrawlThreshold, "max-crawl", config.CrawlThreshold, "Timeout in seconds to scrape and process a single page")
+	flag.IntVar(&options.NumThreads, "max-threads", 0, "Number of goroutines, defaults to NumCPU")
+	flag.StringVar(&config.NodeStore, "node-store", config.NodeStore, "Where crawled pages are kept: memory or bolt")
+	flag.StringVar(&config.NodeStorePath, "node-store-path", config.NodeStorePath, "Path of the bolt database for -node-store=bolt")
+	flag.BoolVar(&config.External.Collect, "external", false, "Collect links to other hosts, never crawled, for the external report and graph")
+	flag.BoolVar(&config.External.Check, "external-check", false, "Check links to other hosts with HEAD or GET (implies external)")
+	flag.IntVar(&config.External.Concurrency, "external-concurrency", config.External.Concurrency, "Most checks of links to other hosts at once")
+	flag.Float64Var(&config.External.Rate, "external-rate", config.External.Rate, "Most checks of links to other hosts started per second, 0 for no limit")
+	outputFlags(flag.CommandLine, &config, &options)
 
 	flag.Lookup("alsologtostderr").Value.Set("true")
 	flag.Parse()
+	return config, options
+}
+
+// Adds the flags of outputs, shared by the crawl and the render subcommand.
+func outputFlags(flags *flag.FlagSet, config *Config, options *Options) {
+	flags.StringVar(&config.Assets.Mode, "assets", config.Assets.Mode, "Static assets in all outputs: "+strings.Join(processor.AssetModes, ", "))
+	flags.IntVar(&config.Assets.MinPages, "assets-min-pages", config.Assets.MinPages, "Pages an asset must be referenced by, more than, for -assets=shared-only")
+
+	flags.BoolVar(&options.GenImage, "gen-image", false, "Generate an image of sitemap (implies gen-graph), default false")
+	flags.BoolVar(&config.GenGraph, "gen-graph", config.GenGraph, "Generate a graphviz graph")
+	flags.IntVar(&config.Graph.ClusterDepth, "graph-cluster-depth", 0, "Group nodes of the graph into clusters by up to this many path segments, 0 for none")
+	flags.BoolVar(&config.Graph.AnchorLabels, "graph-anchor-labels", false, "Label links of the graph with their most common anchor text instead of their count")
+	flags.BoolVar(&config.Graph.Duplicates, "graph-duplicates", false, "Outline pages of the graph with the same content in the same color")
+	flags.BoolVar(&config.Graph.Collapse, "graph-collapse", false, "Draw the innermost clusters of the graph as a single node with their page count")
+	flags.BoolVar(&config.Graph.Insecure, "graph-insecure", false, "Color labels of https pages of the graph with http:// references, red for active mixed content")
+	flags.StringVar(&config.Graph.Label, "graph-label", "", "Page field shown as label of the nodes of the graph: "+strings.Join(processor.DotFields, ", "))
+	flags.BoolVar(&config.Graph.Rank, "graph-rank", false, "Size and color pages of the graph by their PageRank")
+	flags.StringVar(&config.Graph.Tooltip, "graph-tooltip", "", "Page field shown as tooltip of the nodes of the graph: "+strings.Join(processor.DotFields, ", "))
+	flags.StringVar(&options.ShowProg, "display-prog", "", "If not empty, program to display the image (implies gen-graph and gen-image), chromium etc.")
+	flags.StringVar(&options.GraphFormat, "format", "svg", "Format of generated image")
+	flags.IntVar(&options.DiagramMaxNodes, "diagram-max-nodes", 100, "Most nodes drawn in mermaid and plantuml, rest are collapsed, 0 for all")
+	flags.BoolVar(&options.DiagramNoAssets, "diagram-no-assets", false, "Leave static assets out of mermaid and plantuml")
+	flags.IntVar(&options.MaxClickDepth, "max-click-depth", 3, "Click depth beyond which pages are listed in the depth report, 0 for none")
+	flags.StringVar(&options.KnownURLs, "known-urls", "", "Sitemap or list of urls, file or http(s) url, to find orphan pages in the depth report")
+	flags.BoolVar(&options.SitemapPriority, "sitemap-priority", false, "Set priority in sitemap.xml from the depth of pages")
+	flags.StringVar(&options.AuditRules, "audit-rules", "", "Comma separated rules of the audit, all if empty: "+strings.Join(processor.AuditRules, ", "))
+	flags.StringVar(&options.AuditSkip, "audit-skip", "", "Comma separated rules the audit leaves out")
+	flags.StringVar(&options.OutputFormats, "output-format", "", "Comma separated formats to write as dotler.<format> besides dotler.dot: "+strings.Join(OutputFormats, ", "))
 }
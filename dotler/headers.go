@@ -0,0 +1,48 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler security headers of responses.
+package dotler
+
+import (
+	processor "github.com/ronin13/dotler/processor"
+	wire "github.com/ronin13/dotler/wire"
+
+	"net/http"
+	"strings"
+)
+
+// Returns the processor.SecurityHeaders in header, repeated ones joined.
+func securityHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string)
+	for _, name := range processor.SecurityHeaders {
+		if values, exists := header[name]; exists {
+			headers[name] = strings.Join(values, ", ")
+		}
+	}
+	return headers
+}
+
+// Returns the cookies set by resp with their flags. SameSite is
+// lax, strict, none or empty if unset or invalid.
+func cookieFlags(resp *http.Response) []wire.Cookie {
+	var cookies []wire.Cookie
+	for _, cookie := range resp.Cookies() {
+		sameSite := ""
+		switch cookie.SameSite {
+		case http.SameSiteLaxMode:
+			sameSite = "lax"
+		case http.SameSiteStrictMode:
+			sameSite = "strict"
+		case http.SameSiteNoneMode:
+			sameSite = "none"
+		}
+		cookies = append(cookies, wire.Cookie{
+			Name:     cookie.Name,
+			Secure:   cookie.Secure,
+			HTTPOnly: cookie.HttpOnly,
+			SameSite: sameSite,
+		})
+	}
+	return cookies
+}
@@ -11,6 +11,7 @@ import (
 	"log"
 	"net/url"
 	"strings"
+	"sync/atomic"
 )
 
 func panicCrawl(err error) {
@@ -27,6 +28,24 @@ func writeToChan(iPage *wire.Page, inChan chan *wire.Page) {
 	inChan <- iPage
 }
 
+// Sends a page to be crawled, counting it as pending till
+// the Crawl of it returns.
+func (crawler *Crawler) enqueue(iPage *wire.Page, reqChan chan *wire.Page) {
+	atomic.AddInt64(&crawler.pending, 1)
+	writeToChan(iPage, reqChan)
+}
+
+// Marks a pending page as crawled, signals idle when
+// there are no more pages to crawl.
+func (crawler *Crawler) dequeue() {
+	if atomic.AddInt64(&crawler.pending, -1) == 0 {
+		select {
+		case crawler.idle <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // For static assets, get the title as last component
 // Example: http://abcd.com/qq.js returns qq.js
 func getStatTitle(url *url.URL) string {
@@ -11,21 +11,18 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
-	"time"
 )
 
-// Returns content from a url.
-// Uses a timeout on http.Client.
+// Returns content and the response from a url.
+// The response body is already read and closed.
+// Uses the crawler's http.Client which has a timeout.
 // Does not panic, crawling can fail for some pages, doesn't
 // mean we throw crawler with bath water. (to use the pun).
-func getContent(url *url.URL) (string, error) {
-	client := &http.Client{
-		Timeout: time.Duration(ClientTimeout) * time.Second,
-	}
-	resp, err := client.Get(url.String())
+func (crawler *Crawler) getContent(url *url.URL) (string, *http.Response, error) {
+	resp, err := crawler.client.Get(url.String())
 	if err != nil {
 		glog.Infof("Failed to fetch due to %+v", err)
-		return "", err
+		return "", nil, err
 	}
 	defer resp.Body.Close()
 
@@ -34,9 +31,9 @@ func getContent(url *url.URL) (string, error) {
 	if err != nil {
 		// Can happy, don't panic here, try crawling others
 		glog.Infof("Failed to read response %+v", err)
-		return "", err
+		return "", resp, err
 	}
-	return string(body), nil
+	return string(body), resp, nil
 }
 
 // What we consider as a static asset
@@ -0,0 +1,101 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler known urls of a site, for orphan pages.
+package dotler
+
+import (
+	processor "github.com/ronin13/dotler/processor"
+
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sitemaps of a sitemap index are followed this deep.
+const maxSitemapNesting = 2
+
+// Reads location, a file or a http(s) url.
+func readLocation(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return ioutil.ReadFile(location)
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch %s: %s", location, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Returns the urls of a sitemap, a sitemap index or a list of urls,
+// one per line, at location. The urls are normalized the same as
+// the links of crawled pages.
+func loadKnownURLs(location string, nesting int) ([]string, error) {
+	content, err := readLocation(location)
+	if err != nil {
+		return nil, err
+	}
+
+	var links []string
+	if trimmed := bytes.TrimSpace(content); bytes.HasPrefix(trimmed, []byte("<")) {
+		var sitemaps []string
+		links, sitemaps, err = processor.ParseSitemap(bytes.NewReader(trimmed))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse sitemap %s: %s", location, err)
+		}
+		if len(sitemaps) > 0 && nesting >= maxSitemapNesting {
+			return nil, fmt.Errorf("Sitemap index %s nested too deep", location)
+		}
+		for _, sitemap := range sitemaps {
+			nested, err := loadKnownURLs(sitemap, nesting+1)
+			if err != nil {
+				return nil, err
+			}
+			links = append(links, nested...)
+		}
+	} else {
+		links, err = readLines(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	known := make([]string, 0, len(links))
+	for _, link := range links {
+		parsedURL, err := url.Parse(link)
+		if err != nil || !parsedURL.IsAbs() {
+			return nil, fmt.Errorf("Invalid url %s in %s", link, location)
+		}
+		if parsedURL, err = normalizeLink(parsedURL, ""); err != nil {
+			return nil, err
+		}
+		parsedURL.RawQuery = ""
+		parsedURL.Fragment = ""
+		known = append(known, parsedURL.String())
+	}
+	return known, nil
+}
+
+// Non empty lines of in, leaving out # comments.
+func readLines(in io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,83 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler mixed content of https pages.
+package dotler
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	processor "github.com/ronin13/dotler/processor"
+	wire "github.com/ronin13/dotler/wire"
+
+	"strings"
+)
+
+// Elements, with the attribute of their url, loaded by a page,
+// and the severity of loading them over http.
+var subresources = []struct {
+	selector, attr, severity string
+}{
+	{"script[src]", "src", processor.SeverityActive},
+	{"link[href]", "href", processor.SeverityActive},
+	{"iframe[src], frame[src]", "src", processor.SeverityActive},
+	{"object[data]", "data", processor.SeverityActive},
+	{"embed[src]", "src", processor.SeverityActive},
+	{"img[src], audio[src], video[src], source[src], track[src]", "src", processor.SeverityPassive},
+	{"video[poster]", "poster", processor.SeverityPassive},
+}
+
+// Severity of a http:// <link>, only stylesheets are loaded as active
+// content, icons as passive, the rest, canonical, next etc, not at all.
+func linkSeverity(item *goquery.Selection) string {
+	rel, _ := item.Attr("rel")
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		switch value {
+		case "stylesheet", "preload", "modulepreload":
+			return processor.SeverityActive
+		case "icon", "apple-touch-icon":
+			return processor.SeverityPassive
+		}
+	}
+	return ""
+}
+
+// Fills in the http:// references of inPage from doc, if it is https:
+// subresources loaded insecurely and links to http pages of the site.
+func updateInsecure(doc *goquery.Document, inPage *wire.Page) {
+	inPage.Insecure = nil
+	if inPage.PageURL.Scheme != "https" {
+		return
+	}
+	seen := make(map[wire.InsecureRef]bool)
+	add := func(item *goquery.Selection, link string, severity string) {
+		parsedURL, err := inPage.PageURL.Parse(strings.TrimSpace(link))
+		if err != nil || parsedURL.Scheme != "http" {
+			return
+		}
+		ref := wire.InsecureRef{URL: parsedURL.String(), Element: item.Get(0).Data, Severity: severity}
+		if severity == processor.SeverityDowngrade && parsedURL.Hostname() != inPage.PageURL.Hostname() {
+			return
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			inPage.Insecure = append(inPage.Insecure, ref)
+		}
+	}
+
+	for _, resource := range subresources {
+		doc.Find(resource.selector).Each(func(i int, item *goquery.Selection) {
+			severity := resource.severity
+			if item.Is("link") {
+				if severity = linkSeverity(item); severity == "" {
+					return
+				}
+			}
+			link, _ := item.Attr(resource.attr)
+			add(item, link, severity)
+		})
+	}
+	doc.Find("a[href]").Each(func(i int, item *goquery.Selection) {
+		link, _ := item.Attr("href")
+		add(item, link, processor.SeverityDowngrade)
+	})
+}
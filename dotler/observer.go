@@ -0,0 +1,65 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler crawl event observers.
+package dotler
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+)
+
+// DropReason is why a page was not crawled successfully.
+type DropReason int
+
+const (
+	// Skipped pages took longer than Config.CrawlThreshold.
+	Skipped DropReason = iota
+	// Failed pages could not be fetched or parsed.
+	Failed
+	// Cancelled pages were being crawled when the crawl was cancelled.
+	Cancelled
+)
+
+func (reason DropReason) String() string {
+	switch reason {
+	case Skipped:
+		return "skipped"
+	case Failed:
+		return "failed"
+	case Cancelled:
+		return "cancelled"
+	}
+	return "unknown"
+}
+
+// Observer is notified of crawl events, set with Config.Observer.
+// Methods are called from the crawl goroutines concurrently
+// and should return quickly.
+type Observer interface {
+	// PageFetched is called after a page is crawled successfully,
+	// with its OutLinks and StatList filled in.
+	PageFetched(page *wire.Page)
+	// LinkDiscovered is called for every link from one page
+	// to another page of the same host.
+	LinkDiscovered(from, to *wire.Page)
+	// PageDropped is called when crawling a page is given up.
+	PageDropped(page *wire.Page, reason DropReason)
+	// CrawlFinished is called once at the end of Run.
+	CrawlFinished(result *Result)
+}
+
+// BaseObserver implements Observer by doing nothing, embed it
+// to observe only some of the events.
+type BaseObserver struct{}
+
+// PageFetched does nothing.
+func (BaseObserver) PageFetched(*wire.Page) {}
+
+// LinkDiscovered does nothing.
+func (BaseObserver) LinkDiscovered(from, to *wire.Page) {}
+
+// PageDropped does nothing.
+func (BaseObserver) PageDropped(*wire.Page, DropReason) {}
+
+// CrawlFinished does nothing.
+func (BaseObserver) CrawlFinished(*Result) {}
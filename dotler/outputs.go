@@ -0,0 +1,171 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler output files of the command line.
+package dotler
+
+import (
+	processor "github.com/ronin13/dotler/processor"
+	wire "github.com/ronin13/dotler/wire"
+
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// OutputFormats are the formats -output-format accepts,
+// besides dot which is written with -gen-graph.
+// csv is written as nodes.csv and edges.csv, sitemap as sitemap.xml,
+// mermaid as dotler.mmd, plantuml as dotler.puml, rank as rank.csv,
+// depth as depth.txt, duplicates as duplicates.txt, the audit
+// as audit.json or audit.csv, fragments as fragments.txt, mixed
+// as mixed.txt, headers as headers.csv and headers.txt and external
+// as external.txt.
+var OutputFormats = []string{"json", "graphml", "gexf", "csv", "sitemap", "mermaid", "plantuml", "html", "rank", "depth", "duplicates", "audit-json", "audit-csv", "fragments", "mixed", "headers", "external"}
+
+// JSONOnlyFormats need the statuses, headers or external links of
+// pages, which a crawl saved as dotler.dot does not have, and can not
+// be rendered from it.
+var JSONOnlyFormats = []string{"sitemap", "duplicates", "audit-json", "audit-csv", "fragments", "mixed", "headers", "external"}
+
+// outputFiles closes every file of the outputs.
+type outputFiles []io.Closer
+
+func (files outputFiles) Close() error {
+	var firstErr error
+	for _, file := range files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Creates the file of format, usually dotler.<format>,
+// and returns the processor writing to it.
+func newOutput(format string, options Options) (wire.GraphProcessor, io.Closer, error) {
+	var newProc func(io.Writer) wire.GraphProcessor
+	name := "dotler." + format
+	diagram := processor.DiagramOptions{NoAssets: options.DiagramNoAssets, MaxNodes: options.DiagramMaxNodes}
+	switch format {
+	case "json":
+		newProc = processor.NewJSON
+	case "graphml":
+		newProc = processor.NewGraphML
+	case "gexf":
+		newProc = processor.NewGEXF
+	case "html":
+		newProc = processor.NewHTML
+	case "csv":
+		nodes, err := os.Create("nodes.csv")
+		if err != nil {
+			return nil, nil, err
+		}
+		edges, err := os.Create("edges.csv")
+		if err != nil {
+			nodes.Close()
+			return nil, nil, err
+		}
+		return processor.NewCSV(nodes, edges), outputFiles{nodes, edges}, nil
+	case "sitemap":
+		// Closes the files it creates.
+		return processor.NewSitemap(createFile, 0, options.SitemapPriority), outputFiles{}, nil
+	case "mermaid":
+		name = "dotler.mmd"
+		newProc = func(out io.Writer) wire.GraphProcessor { return processor.NewMermaid(out, diagram) }
+	case "plantuml":
+		name = "dotler.puml"
+		newProc = func(out io.Writer) wire.GraphProcessor { return processor.NewPlantUML(out, diagram) }
+	case "rank":
+		name = "rank.csv"
+		newProc = processor.NewRankReport
+	case "depth":
+		depthOptions := processor.DepthOptions{MaxDepth: options.MaxClickDepth}
+		if options.KnownURLs != "" {
+			known, err := loadKnownURLs(options.KnownURLs, 0)
+			if err != nil {
+				return nil, nil, err
+			}
+			depthOptions.Known = known
+		}
+		name = "depth.txt"
+		newProc = func(out io.Writer) wire.GraphProcessor { return processor.NewDepthReport(out, depthOptions) }
+	case "duplicates":
+		name = "duplicates.txt"
+		newProc = processor.NewDuplicateReport
+	case "audit-json", "audit-csv":
+		audit := processor.AuditOptions{Rules: splitList(options.AuditRules), Skip: splitList(options.AuditSkip)}
+		if err := audit.Validate(); err != nil {
+			return nil, nil, err
+		}
+		name = "audit.json"
+		newProc = func(out io.Writer) wire.GraphProcessor { return processor.NewAuditJSON(out, audit) }
+		if format == "audit-csv" {
+			name = "audit.csv"
+			newProc = func(out io.Writer) wire.GraphProcessor { return processor.NewAuditCSV(out, audit) }
+		}
+	case "fragments":
+		name = "fragments.txt"
+		newProc = processor.NewFragmentReport
+	case "mixed":
+		name = "mixed.txt"
+		newProc = processor.NewMixedContentReport
+	case "headers":
+		matrix, err := os.Create("headers.csv")
+		if err != nil {
+			return nil, nil, err
+		}
+		summary, err := os.Create("headers.txt")
+		if err != nil {
+			matrix.Close()
+			return nil, nil, err
+		}
+		return processor.NewHeaderAudit(matrix, summary), outputFiles{matrix, summary}, nil
+	case "external":
+		name = "external.txt"
+		newProc = processor.NewExternalReport
+	case "dot":
+		return nil, nil, fmt.Errorf("dot is written with -gen-graph")
+	}
+	if newProc == nil {
+		return nil, nil, fmt.Errorf("Unknown output format %s, need one of %s", format, strings.Join(OutputFormats, ", "))
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newProc(file), file, nil
+}
+
+// Non empty items of the comma separated list.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func createFile(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+// Returns processors for the comma separated options.OutputFormats,
+// keyed by format.
+func newOutputs(options Options) (map[string]wire.GraphProcessor, outputFiles, error) {
+	var files outputFiles
+	procs := make(map[string]wire.GraphProcessor)
+	for _, format := range splitList(options.OutputFormats) {
+		proc, file, err := newOutput(format, options)
+		if err != nil {
+			files.Close()
+			return nil, nil, err
+		}
+		procs[format] = proc
+		files = append(files, file)
+	}
+	return procs, files, nil
+}
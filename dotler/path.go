@@ -0,0 +1,141 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler path subcommand.
+package dotler
+
+import (
+	processor "github.com/ronin13/dotler/processor"
+	wire "github.com/ronin13/dotler/wire"
+
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Reads a crawl saved as JSON or as dotler.dot from file.
+func loadCrawl(file string) (*processor.JSONDocument, error) {
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	doc, err := processor.LoadGraph(in)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load %s: %s", file, err)
+	}
+	return doc, nil
+}
+
+// Normalizes link the same as the urls of crawled pages,
+// or returns it as is if it is not a url.
+func normalizeArg(link string) string {
+	parsedURL, err := url.Parse(link)
+	if err != nil || !parsedURL.IsAbs() {
+		return link
+	}
+	if parsedURL, err = normalizeLink(parsedURL, ""); err != nil {
+		return link
+	}
+	return parsedURL.String()
+}
+
+// Writes the graphviz graph of doc to dest, and renders
+// it to an image in format next to it, if format is set.
+func writeDocGraph(doc *processor.JSONDocument, graphOptions processor.DotOptions, dest string, format string) error {
+	var graph bytes.Buffer
+	errs, err := replayCrawl(doc, map[string]wire.GraphProcessor{
+		graphProcessor: processor.NewDotPrinter(&graph, graphOptions),
+	}, DefaultConfig())
+	if err != nil {
+		return err
+	}
+	if err = errs[graphProcessor]; err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(dest, graph.Bytes(), 0644); err != nil {
+		return err
+	}
+	if format != "" {
+		return renderGraph(graph.String(), format, strings.TrimSuffix(dest, ".dot")+"."+format)
+	}
+	return nil
+}
+
+// PathCommand is the path subcommand, which prints the shortest paths
+// of links between two pages of a crawl saved as JSON or dotler.dot,
+// one per line. Returns the exit code.
+// Usage of ./dotler path [flags] dotler.json:
+//
+//	-all
+//	      Print all the shortest paths, not just one
+//	-dot string
+//	      If not empty, file to write the graph of the paths to
+//	-format string
+//	      If not empty, format of an image of the graph of the paths, svg etc., needs -dot
+//	-from string
+//	      Url to start from (default root url of a JSON crawl)
+//	-max int
+//	      Most paths printed with -all (default 20)
+//	-to string
+//	      Url to reach
+func PathCommand(args []string, stdout io.Writer) int {
+	flags := flag.NewFlagSet("path", flag.ContinueOnError)
+	from := flags.String("from", "", "Url to start from (default root url of a JSON crawl)")
+	to := flags.String("to", "", "Url to reach")
+	all := flags.Bool("all", false, "Print all the shortest paths, not just one")
+	max := flags.Int("max", 20, "Most paths printed with -all")
+	dotFile := flags.String("dot", "", "If not empty, file to write the graph of the paths to")
+	format := flags.String("format", "", "If not empty, format of an image of the graph of the paths, svg etc., needs -dot")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || *to == "" {
+		fmt.Fprintln(os.Stderr, "Usage: dotler path [-from url] -to url [-all] [-dot file] [-format svg] dotler.json|dotler.dot")
+		return 2
+	}
+	if *format != "" && *dotFile == "" {
+		fmt.Fprintln(os.Stderr, "Need -dot for -format, the image is rendered next to it")
+		return 2
+	}
+
+	doc, err := loadCrawl(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *from == "" {
+		*from = doc.RootURL
+	}
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, "Need -from, the graph has no root url")
+		return 2
+	}
+
+	limit := 1
+	if *all {
+		limit = *max
+	}
+	source, target := normalizeArg(*from), normalizeArg(*to)
+	paths := processor.ShortestPaths(doc.Edges, source, target, limit)
+	if paths == nil {
+		fmt.Fprintf(os.Stderr, "No path from %s to %s\n", source, target)
+		return 1
+	}
+	for _, path := range paths {
+		fmt.Fprintln(stdout, strings.Join(path, " -> "))
+	}
+
+	if *dotFile != "" {
+		if err = writeDocGraph(doc.Subgraph(paths), processor.DotOptions{}, *dotFile, *format); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %s\n", *dotFile, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -12,45 +12,57 @@ import (
 	"io"
 	"os"
 	"os/exec"
-	"strings"
 )
 
-func postProcess(result string) int {
+// Renders graph to dest in format with dot (from graphviz).
+func renderGraph(graph string, format string, dest string) error {
 
 	var err error
 	var graphPipe io.WriteCloser
 
-	glog.Infof("Generating svg from dot file")
-	cmdLine := strings.Split(fmt.Sprintf("-T%s -o dotler.%s", graphFormat, graphFormat), " ")
-	graphIt := exec.Command("dot", cmdLine...)
+	graphIt := exec.Command("dot", "-T"+format, "-o", dest)
 	graphIt.Stdout = os.Stdout
 	graphIt.Stderr = os.Stderr
 
 	graphPipe, err = graphIt.StdinPipe()
-	panicCrawl(err)
+	if err != nil {
+		return err
+	}
+
+	if err = graphIt.Start(); err != nil {
+		return err
+	}
 
-	panicCrawl(graphIt.Start())
+	_, err = graphPipe.Write([]byte(graph))
+	if closeErr := graphPipe.Close(); err == nil {
+		err = closeErr
+	}
+	if waitErr := graphIt.Wait(); err == nil {
+		err = waitErr
+	}
+	return err
+}
 
-	_, err = graphPipe.Write([]byte(result))
-	panicCrawl(err)
+func postProcess(result string, options Options) int {
 
-	panicCrawl(graphPipe.Close())
+	var err error
 
-	err = graphIt.Wait()
+	glog.Infof("Generating svg from dot file")
+	err = renderGraph(result, options.GraphFormat, fmt.Sprintf("dotler.%s", options.GraphFormat))
 	if err != nil {
 		glog.Fatalf("dotler.svg generation failed!")
 		return 1
 	}
-	if showProg != "" {
-		glog.Infof("Displaying image with %s", showProg)
-		destFile := fmt.Sprintf("dotler.%s", graphFormat)
-		showIt := exec.Command(showProg, destFile)
+	if options.ShowProg != "" {
+		glog.Infof("Displaying image with %s", options.ShowProg)
+		destFile := fmt.Sprintf("dotler.%s", options.GraphFormat)
+		showIt := exec.Command(options.ShowProg, destFile)
 		showIt.Stdout = os.Stdout
 		showIt.Stderr = os.Stderr
 		panicCrawl(showIt.Start())
 		err = showIt.Wait()
 		if err != nil {
-			glog.Fatalf("Display of image with %s failed", showProg)
+			glog.Fatalf("Display of image with %s failed", options.ShowProg)
 			return 1
 		}
 	}
@@ -0,0 +1,129 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler render subcommand.
+package dotler
+
+import (
+	"github.com/golang/glog"
+	processor "github.com/ronin13/dotler/processor"
+	wire "github.com/ronin13/dotler/wire"
+
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// Feeds the pages of doc to procs, with assets filtered as in
+// config, as a crawl would. Returns the errors of failed processors,
+// keyed by their name.
+func replayCrawl(doc *processor.JSONDocument, procs map[string]wire.GraphProcessor, config Config) (map[string]error, error) {
+	pages, err := doc.Pages()
+	if err != nil {
+		return nil, err
+	}
+	fanOut := processor.NewFanOut(procs, nil, config.Assets)
+	pageChan := make(chan *wire.Page)
+	fanOut.ProcessLoop(pageChan)
+	for _, iPage := range pages {
+		pageChan <- iPage
+	}
+	close(pageChan)
+	return fanOut.Finish(&wire.CrawlInfo{
+		RootURL:   doc.RootURL,
+		StartTime: doc.StartTime,
+		EndTime:   doc.EndTime,
+		Stats:     wire.Stats(doc.Statistics),
+		External:  doc.LinkChecks(),
+	}), nil
+}
+
+// RenderCommand is the render subcommand, which writes the outputs of
+// a crawl saved as JSON or dotler.dot again, without crawling the site.
+// It takes the output flags of a crawl, -output-format, -assets, -graph-*,
+// -gen-image etc. and writes the same files. The JSONOnlyFormats
+// are rejected for a dotler.dot. Returns the exit code.
+// Usage of ./dotler render [flags] dotler.json
+func RenderCommand(args []string) int {
+	config := DefaultConfig()
+	var options Options
+	flags := flag.NewFlagSet("render", flag.ContinueOnError)
+	outputFlags(flags, &config, &options)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: dotler render [flags] dotler.json|dotler.dot")
+		fmt.Fprintf(os.Stderr, "The output formats %s need dotler.json\n", strings.Join(JSONOnlyFormats, ", "))
+		return 2
+	}
+
+	setup(&config, &options)
+	if err := config.Assets.Validate(); err != nil {
+		glog.Errorf("Invalid configuration: %s", err)
+		return 2
+	}
+	if err := config.Graph.Validate(); err != nil {
+		glog.Errorf("Invalid configuration: %s", err)
+		return 2
+	}
+
+	// Read before dotler.dot is written, it may be the input.
+	doc, err := loadCrawl(flags.Arg(0))
+	if err != nil {
+		glog.Errorln(err)
+		return 2
+	}
+	// Only a graphviz graph has no root url.
+	if doc.RootURL == "" {
+		for _, format := range splitList(options.OutputFormats) {
+			for _, jsonOnly := range JSONOnlyFormats {
+				if format == jsonOnly {
+					glog.Errorf("Output %s needs a crawl saved as JSON, %s has no statuses of pages", format, flags.Arg(0))
+					return 2
+				}
+			}
+		}
+	}
+
+	procs, files, err := newOutputs(options)
+	if err != nil {
+		glog.Errorf("Invalid output format: %s", err)
+		return 2
+	}
+	defer files.Close()
+	var graph bytes.Buffer
+	if config.GenGraph {
+		procs[graphProcessor] = processor.NewDotPrinter(&graph, config.Graph)
+	}
+
+	errs, err := replayCrawl(doc, procs, config)
+	if err != nil {
+		glog.Errorf("Invalid crawl %s: %s", flags.Arg(0), err)
+		return 2
+	}
+	status := 0
+	for name, err := range errs {
+		glog.Errorf("Output %s failed: %s", name, err)
+		status = 1
+	}
+	if err = files.Close(); err != nil {
+		glog.Errorf("Failed to write outputs: %s", err)
+		status = 1
+	}
+
+	if config.GenGraph && errs[graphProcessor] == nil {
+		if err = ioutil.WriteFile("dotler.dot", graph.Bytes(), 0644); err != nil {
+			glog.Errorf("Failed to write dotler.dot: %s", err)
+			return 1
+		}
+		glog.Infof("Rendered %s to dotler.dot\n", flags.Arg(0))
+		if options.GenImage && postProcess(graph.String(), options) != 0 {
+			return 1
+		}
+	}
+	return status
+}
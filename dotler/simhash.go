@@ -0,0 +1,42 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+// Package dotler fingerprints of the text of pages.
+package dotler
+
+import (
+	"hash/fnv"
+	"strings"
+)
+
+// Words per shingle hashed by simHash.
+const shingleSize = 3
+
+// Returns the 64 bit SimHash of words, over shingles of shingleSize
+// lowercased words, so pages with nearly the same text have hashes
+// a few bits apart. Returns 0 if there are too few words.
+func simHash(words []string) uint64 {
+	if len(words) < shingleSize {
+		return 0
+	}
+	var weights [64]int
+	for i := 0; i+shingleSize <= len(words); i++ {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.ToLower(strings.Join(words[i:i+shingleSize], " "))))
+		sum := hash.Sum64()
+		for bit := uint(0); bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for bit := uint(0); bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
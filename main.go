@@ -9,6 +9,16 @@ import (
 )
 
 func main() {
-	dotler.ParseFlags()
-	os.Exit(dotler.StartCrawl(dotler.RootURL))
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "path":
+			os.Exit(dotler.PathCommand(os.Args[2:], os.Stdout))
+		case "diff":
+			os.Exit(dotler.DiffCommand(os.Args[2:], os.Stdout))
+		case "render":
+			os.Exit(dotler.RenderCommand(os.Args[2:]))
+		}
+	}
+	config, options := dotler.ParseFlags()
+	os.Exit(dotler.StartCrawl(config, options))
 }
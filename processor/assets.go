@@ -0,0 +1,101 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Modes of AssetOptions, how static assets of pages are
+// handed to the processors.
+const (
+	// AssetsFull hands over every asset.
+	AssetsFull = "full"
+	// AssetsNone leaves out all the assets, pages only.
+	AssetsNone = "none"
+	// AssetsAggregate replaces the assets of a page by a single
+	// asset, <page url>#assets, titled with their counts by type.
+	AssetsAggregate = "aggregate"
+	// AssetsShared keeps only the assets referenced by more than
+	// AssetOptions.MinPages pages. Pages are only processed at the
+	// end of the crawl, when this is known, loaded back if spilled.
+	AssetsShared = "shared-only"
+)
+
+// AssetModes are the valid AssetOptions.Mode.
+var AssetModes = []string{AssetsFull, AssetsNone, AssetsAggregate, AssetsShared}
+
+// AssetOptions control the static assets of pages, for all the
+// processors of a FanOut.
+type AssetOptions struct {
+	// Mode is one of AssetModes, empty for AssetsFull.
+	Mode string
+	// MinPages is the number of pages an asset must be
+	// referenced by, more than, for AssetsShared.
+	MinPages int
+}
+
+// Validate checks the mode of options.
+func (options AssetOptions) Validate() error {
+	if options.Mode == "" {
+		return nil
+	}
+	for _, mode := range AssetModes {
+		if options.Mode == mode {
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown asset mode %s, need one of %s", options.Mode, strings.Join(AssetModes, ", "))
+}
+
+// Type of an asset from its extension, other if it has none.
+func assetType(sPage wire.StatPage) string {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(sPage.StaticURL.Path), "."))
+	if ext == "" {
+		return "other"
+	}
+	return ext
+}
+
+// Returns iPage with its assets as per options.Mode, refs being the
+// number of pages referencing every asset for AssetsShared.
+// iPage itself is never modified, it may still be in the node map.
+func (options AssetOptions) filter(iPage *wire.Page, refs map[string]int) *wire.Page {
+	if options.Mode == "" || options.Mode == AssetsFull || len(iPage.StatList) == 0 {
+		return iPage
+	}
+	fPage := *iPage
+	fPage.StatList = make(map[string]wire.StatPage)
+
+	switch options.Mode {
+	case AssetsAggregate:
+		counts := make(map[string]int)
+		for _, sPage := range iPage.StatList {
+			counts[assetType(sPage)]++
+		}
+		types := make([]string, 0, len(counts))
+		for aType, count := range counts {
+			types = append(types, fmt.Sprintf("%s %d", aType, count))
+		}
+		sort.Strings(types)
+		aggURL := *iPage.PageURL
+		aggURL.Fragment = "assets"
+		fPage.StatList[aggURL.String()] = wire.StatPage{
+			PageTitle: fmt.Sprintf("%d assets: %s", len(iPage.StatList), strings.Join(types, ", ")),
+			StaticURL: &aggURL,
+		}
+	case AssetsShared:
+		for key, sPage := range iPage.StatList {
+			if refs[key] > options.MinPages {
+				fPage.StatList[key] = sPage
+			}
+		}
+	}
+	return &fPage
+}
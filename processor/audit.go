@@ -0,0 +1,228 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Rules of the on-page audit.
+const (
+	RuleMissingTitle         = "missing-title"
+	RuleDuplicateTitle       = "duplicate-title"
+	RuleLongTitle            = "long-title"
+	RuleMissingDescription   = "missing-description"
+	RuleDuplicateDescription = "duplicate-description"
+	RuleMissingH1            = "missing-h1"
+	RuleMultipleH1           = "multiple-h1"
+	RuleImageAlt             = "image-alt"
+	RuleMissingCanonical     = "missing-canonical"
+	RuleLinkedNoIndex        = "linked-noindex"
+)
+
+// AuditRules are all the rules of the audit, in the order reported.
+var AuditRules = []string{
+	RuleMissingTitle, RuleDuplicateTitle, RuleLongTitle,
+	RuleMissingDescription, RuleDuplicateDescription,
+	RuleMissingH1, RuleMultipleH1, RuleImageAlt,
+	RuleMissingCanonical, RuleLinkedNoIndex,
+}
+
+var auditDescriptions = map[string]string{
+	RuleMissingTitle:         "Pages without a <title>",
+	RuleDuplicateTitle:       "Pages with the same <title> as another page",
+	RuleLongTitle:            fmt.Sprintf("Pages with a <title> longer than %d characters", MaxTitleLength),
+	RuleMissingDescription:   "Pages without a meta description",
+	RuleDuplicateDescription: "Pages with the same meta description as another page",
+	RuleMissingH1:            "Pages without a <h1>",
+	RuleMultipleH1:           "Pages with more than one <h1>",
+	RuleImageAlt:             "Pages with images without alt",
+	RuleMissingCanonical:     "Pages without a canonical link",
+	RuleLinkedNoIndex:        "Pages with noindex which other pages link to",
+}
+
+// MaxTitleLength is the most characters of a title before it is
+// reported as long, search engines cut them off around it.
+const MaxTitleLength = 60
+
+// AuditOptions choose the rules of the audit.
+type AuditOptions struct {
+	// Rules are the rules checked, all AuditRules if empty.
+	Rules []string
+	// Skip are rules left out.
+	Skip []string
+}
+
+// Validate checks options for errors.
+func (options AuditOptions) Validate() error {
+	for _, rule := range append(append([]string{}, options.Rules...), options.Skip...) {
+		if _, exists := auditDescriptions[rule]; !exists {
+			return fmt.Errorf("Unknown audit rule %s, need one of %s", rule, strings.Join(AuditRules, ", "))
+		}
+	}
+	return nil
+}
+
+// Returns the rules checked with options, in the order of AuditRules.
+func (options AuditOptions) rules() []string {
+	enabled := make(map[string]bool)
+	for _, rule := range options.Rules {
+		enabled[rule] = true
+	}
+	for _, rule := range options.Skip {
+		enabled[rule] = false
+	}
+	var rules []string
+	for _, rule := range AuditRules {
+		if checked, exists := enabled[rule]; checked || (!exists && len(options.Rules) == 0) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// AuditIssue is a page failing a rule, with Detail, if any, on how.
+type AuditIssue struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// AuditResult is the pages failing a rule, by url.
+type AuditResult struct {
+	Rule        string       `json:"rule"`
+	Description string       `json:"description"`
+	Issues      []AuditIssue `json:"issues"`
+}
+
+// Audit checks the rules of options on the pages in nodes, only the
+// crawled ones with a 2xx status. Returns a result for every rule
+// checked, with no issues if all the pages pass.
+func Audit(nodes []Node, edges []Edge, options AuditOptions) []AuditResult {
+	var pages []Node
+	titles := make(map[string]int)
+	descriptions := make(map[string]int)
+	for _, node := range nodes {
+		if node.Type == NodePage && node.Status >= 200 && node.Status < 300 {
+			pages = append(pages, node)
+			titles[node.Title]++
+			descriptions[node.Description]++
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].URL < pages[j].URL
+	})
+	// Links to a page from other pages.
+	inLinks := make(map[string]int)
+	for _, edge := range edges {
+		if edge.Kind == EdgeLink && edge.Source != edge.Target {
+			inLinks[edge.Target]++
+		}
+	}
+
+	checks := map[string]func(Node) (bool, string){
+		RuleMissingTitle: func(page Node) (bool, string) {
+			return page.Title == "", ""
+		},
+		RuleDuplicateTitle: func(page Node) (bool, string) {
+			return page.Title != "" && titles[page.Title] > 1, page.Title
+		},
+		RuleLongTitle: func(page Node) (bool, string) {
+			length := len([]rune(page.Title))
+			return length > MaxTitleLength, strconv.Itoa(length)
+		},
+		RuleMissingDescription: func(page Node) (bool, string) {
+			return page.Description == "", ""
+		},
+		RuleDuplicateDescription: func(page Node) (bool, string) {
+			return page.Description != "" && descriptions[page.Description] > 1, page.Description
+		},
+		RuleMissingH1: func(page Node) (bool, string) {
+			return page.H1Count == 0, ""
+		},
+		RuleMultipleH1: func(page Node) (bool, string) {
+			return page.H1Count > 1, strconv.Itoa(page.H1Count)
+		},
+		RuleImageAlt: func(page Node) (bool, string) {
+			return page.MissingAlt > 0, strconv.Itoa(page.MissingAlt)
+		},
+		RuleMissingCanonical: func(page Node) (bool, string) {
+			return page.Canonical == "", ""
+		},
+		RuleLinkedNoIndex: func(page Node) (bool, string) {
+			return page.NoIndex && inLinks[page.URL] > 0, strconv.Itoa(inLinks[page.URL])
+		},
+	}
+
+	var results []AuditResult
+	for _, rule := range options.rules() {
+		result := AuditResult{Rule: rule, Description: auditDescriptions[rule], Issues: []AuditIssue{}}
+		for _, page := range pages {
+			if failed, detail := checks[rule](page); failed {
+				result.Issues = append(result.Issues, AuditIssue{URL: page.URL, Detail: detail})
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// AuditVersion is the version of the AuditDocument schema.
+const AuditVersion = 1
+
+// AuditDocument is the audit written by NewAuditJSON.
+type AuditDocument struct {
+	Version int           `json:"version"`
+	RootURL string        `json:"root_url"`
+	Results []AuditResult `json:"results"`
+}
+
+type auditPrinter struct {
+	graph   *graph
+	options AuditOptions
+	write   func(info *wire.CrawlInfo, results []AuditResult) error
+}
+
+// NewAuditJSON returns a GraphProcessor which writes the results
+// of Audit as an AuditDocument to out.
+func NewAuditJSON(out io.Writer, options AuditOptions) wire.GraphProcessor {
+	return &auditPrinter{graph: newGraph(), options: options, write: func(info *wire.CrawlInfo, results []AuditResult) error {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(&AuditDocument{Version: AuditVersion, RootURL: info.RootURL, Results: results})
+	}}
+}
+
+// NewAuditCSV returns a GraphProcessor which writes the results
+// of Audit to out, a row of rule, url and detail per issue.
+func NewAuditCSV(out io.Writer, options AuditOptions) wire.GraphProcessor {
+	return &auditPrinter{graph: newGraph(), options: options, write: func(info *wire.CrawlInfo, results []AuditResult) error {
+		writer := csv.NewWriter(out)
+		writer.Write([]string{"rule", "url", "detail"})
+		for _, result := range results {
+			for _, issue := range result.Issues {
+				writer.Write([]string{result.Rule, issue.URL, issue.Detail})
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}}
+}
+
+func (audit *auditPrinter) ProcessPage(iPage *wire.Page) error {
+	audit.graph.addPage(iPage)
+	return nil
+}
+
+func (audit *auditPrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, edges := audit.graph.sorted()
+	return audit.write(info, Audit(nodes, edges, audit.options))
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Returns the path prefixes of nodeURL, outermost first, up to depth
+// segments. Nodes on other hosts than the root are kept apart under
+// their host.
+func clusterPrefixes(nodeURL, rootURL *url.URL, depth int) []string {
+	var prefixes []string
+	prefix := "/"
+	if nodeURL.Host != rootURL.Host {
+		prefix = nodeURL.Host + "/"
+		prefixes = append(prefixes, prefix)
+	}
+	for i, segment := range strings.Split(strings.Trim(nodeURL.Path, "/"), "/") {
+		if segment == "" || i >= depth {
+			break
+		}
+		prefix += segment + "/"
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes
+}
+
+// Adds the nodes and edges kept by addNode and addEdge
+// to nested subgraphs, one per path prefix.
+func (dot *dotPrinter) addClusters(rootURL *url.URL) {
+	nodePrefixes := make(map[string][]string, len(dot.nodes))
+	members := make(map[string]int)
+	for name, node := range dot.nodes {
+		prefixes := clusterPrefixes(node.url, rootURL, dot.options.ClusterDepth)
+		nodePrefixes[name] = prefixes
+		for _, prefix := range prefixes {
+			members[prefix]++
+		}
+	}
+
+	// Innermost cluster of every node and the parent of every cluster,
+	// "" being the graph itself.
+	nodeCluster := make(map[string]string, len(dot.nodes))
+	clusterParent := make(map[string]string)
+	hasSub := make(map[string]bool)
+	for name, prefixes := range nodePrefixes {
+		parent := ""
+		for _, prefix := range prefixes {
+			if members[prefix] < 2 {
+				continue
+			}
+			clusterParent[prefix] = parent
+			hasSub[parent] = true
+			parent = prefix
+		}
+		nodeCluster[name] = parent
+	}
+
+	clusters := make([]string, 0, len(clusterParent))
+	for prefix := range clusterParent {
+		clusters = append(clusters, prefix)
+	}
+	sort.Strings(clusters)
+	graphNames := map[string]string{"": "dotler"}
+	for i, prefix := range clusters {
+		graphNames[prefix] = fmt.Sprintf("cluster_%d", i)
+	}
+
+	// Collapsed clusters are replaced by a node of the same name.
+	collapsed := make(map[string]string)
+	if dot.options.Collapse {
+		pages := make(map[string]int)
+		for name, cluster := range nodeCluster {
+			if cluster != "" && !hasSub[cluster] && !dot.nodes[name].static {
+				pages[cluster]++
+			}
+		}
+		for _, prefix := range clusters {
+			if hasSub[prefix] {
+				continue
+			}
+			collapsed[prefix] = fmt.Sprintf("%q", prefix)
+			dot.cgraph.AddNode(graphNames[clusterParent[prefix]], collapsed[prefix], map[string]string{
+				"label": fmt.Sprintf("%q", fmt.Sprintf("%s\n%d pages", prefix, pages[prefix])),
+				"shape": "folder",
+			})
+		}
+	}
+
+	for _, prefix := range clusters {
+		if _, exists := collapsed[prefix]; exists {
+			continue
+		}
+		dot.cgraph.AddSubGraph(graphNames[clusterParent[prefix]], graphNames[prefix], map[string]string{
+			"label": fmt.Sprintf("%q", prefix),
+		})
+	}
+
+	nodeNames := make(map[string]string, len(dot.nodes))
+	for name, node := range dot.nodes {
+		if summary, exists := collapsed[nodeCluster[name]]; exists {
+			nodeNames[name] = summary
+			continue
+		}
+		nodeNames[name] = name
+		dot.cgraph.AddNode(graphNames[nodeCluster[name]], name, node.attrs)
+	}
+
+	// Edges between collapsed clusters are merged, summing up the
+	// link cardinality, and those within one are left out.
+	type edgeKey struct{ src, dst string }
+	merged := make(map[edgeKey]uint)
+	var edges []dotEdge
+	for _, edge := range dot.edges {
+		src, dst := nodeNames[edge.src], nodeNames[edge.dst]
+		if src == edge.src && dst == edge.dst {
+			dot.cgraph.AddEdge(src, dst, true, edge.attrs)
+			continue
+		}
+		if src == dst {
+			continue
+		}
+		key := edgeKey{src: src, dst: dst}
+		if _, exists := merged[key]; !exists {
+			edges = append(edges, dotEdge{src: src, dst: dst, attrs: edge.attrs})
+		}
+		merged[key] += edge.card
+	}
+	for _, edge := range edges {
+		attrs := edge.attrs
+		if card := merged[edgeKey{src: edge.src, dst: edge.dst}]; card > 0 {
+			attrs = map[string]string{"label": strconv.Itoa(int(card))}
+		}
+		dot.cgraph.AddEdge(edge.src, edge.dst, true, attrs)
+	}
+}
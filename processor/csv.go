@@ -0,0 +1,138 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	csvNodeHeader = []string{"url", "type", "title", "status", "description", "h1", "lang", "word_count"}
+	csvEdgeHeader = []string{"source", "target", "card", "kind", "anchor", "rel", "regions"}
+)
+
+// csvPrinter streams rows as pages arrive, only urls
+// are remembered to write every node once.
+type csvPrinter struct {
+	nodes   *csv.Writer
+	edges   *csv.Writer
+	header  bool
+	written map[string]bool
+	// Linked pages without a row yet.
+	pending map[string]bool
+}
+
+// NewCSV returns a GraphProcessor which writes one row per page
+// or static asset to nodes and one row per link to edges.
+// Pages which were linked to but never crawled are written at Finish.
+// Rows are written before the click depth of a node is known, see
+// NewDepthReport for it.
+func NewCSV(nodes, edges io.Writer) wire.GraphProcessor {
+	return &csvPrinter{
+		nodes:   csv.NewWriter(nodes),
+		edges:   csv.NewWriter(edges),
+		written: make(map[string]bool),
+		pending: make(map[string]bool),
+	}
+}
+
+func (csvP *csvPrinter) writeHeader() {
+	if !csvP.header {
+		csvP.nodes.Write(csvNodeHeader)
+		csvP.edges.Write(csvEdgeHeader)
+		csvP.header = true
+	}
+}
+
+func (csvP *csvPrinter) writeNode(node Node) {
+	csvP.nodes.Write([]string{node.URL, node.Type, node.Title, strconv.Itoa(node.Status), node.Description, node.H1, node.Lang, strconv.Itoa(node.WordCount)})
+	csvP.written[node.URL] = true
+	delete(csvP.pending, node.URL)
+}
+
+func (csvP *csvPrinter) flush() error {
+	csvP.nodes.Flush()
+	csvP.edges.Flush()
+	if err := csvP.nodes.Error(); err != nil {
+		return err
+	}
+	return csvP.edges.Error()
+}
+
+func (csvP *csvPrinter) ProcessPage(iPage *wire.Page) error {
+	csvP.writeHeader()
+
+	presURL := iPage.PageURL.String()
+	csvP.writeNode(pageNode(iPage))
+
+	links := make([]string, 0, len(iPage.OutLinks))
+	for link := range iPage.OutLinks {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	for _, link := range links {
+		oPage := iPage.OutLinks[link]
+		addedURL := oPage.Page.PageURL.String()
+		if !csvP.written[addedURL] {
+			csvP.pending[addedURL] = true
+		}
+		edge := linkEdge(presURL, oPage)
+		var rels, regions []string
+		for _, link := range edge.Links {
+			rels = append(rels, link.Rel...)
+			regions = append(regions, link.Region)
+		}
+		csvP.edges.Write([]string{presURL, addedURL, strconv.Itoa(int(oPage.Card)), EdgeLink, edge.Anchor, distinct(rels), distinct(regions)})
+	}
+
+	links = links[:0]
+	for link := range iPage.StatList {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	for _, link := range links {
+		sPage := iPage.StatList[link]
+		addedURL := sPage.StaticURL.String()
+		if !csvP.written[addedURL] {
+			csvP.writeNode(Node{URL: addedURL, Type: NodeAsset, Title: sPage.PageTitle})
+		}
+		csvP.edges.Write([]string{presURL, addedURL, "1", EdgeAsset, "", "", ""})
+	}
+
+	return csvP.flush()
+}
+
+// Returns the sorted distinct non empty values, space separated.
+func distinct(values []string) string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, " ")
+}
+
+func (csvP *csvPrinter) Finish(*wire.CrawlInfo) error {
+	csvP.writeHeader()
+
+	links := make([]string, 0, len(csvP.pending))
+	for link := range csvP.pending {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	for _, link := range links {
+		csvP.writeNode(Node{URL: link, Type: NodePage})
+	}
+	return csvP.flush()
+}
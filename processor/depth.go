@@ -0,0 +1,160 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ClickDepths returns the least number of clicks from root to every
+// page in nodes reachable over the links in edges.
+// Unlike the depth a page was first discovered at by concurrent
+// crawls, wire.Page.Depth, this is always the shortest.
+func ClickDepths(nodes []Node, edges []Edge, root string) map[string]int {
+	out := make(map[string][]string)
+	for _, edge := range edges {
+		if edge.Kind == EdgeLink {
+			out[edge.Source] = append(out[edge.Source], edge.Target)
+		}
+	}
+
+	depths := make(map[string]int)
+	for _, node := range nodes {
+		if node.URL != root || node.Type != NodePage {
+			continue
+		}
+		depths[root] = 0
+		queue := []string{root}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, next := range out[current] {
+				if _, seen := depths[next]; !seen {
+					depths[next] = depths[current] + 1
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	return depths
+}
+
+// Returns the click depths of ClickDepths, with assets one
+// more than the nearest page using them.
+func nodeDepths(nodes []Node, edges []Edge, root string) map[string]int {
+	depths := ClickDepths(nodes, edges, root)
+	for _, edge := range edges {
+		depth, reached := depths[edge.Source]
+		if edge.Kind != EdgeAsset || !reached {
+			continue
+		}
+		if assetDepth, exists := depths[edge.Target]; !exists || depth+1 < assetDepth {
+			depths[edge.Target] = depth + 1
+		}
+	}
+	return depths
+}
+
+// Sets the Depth of nodes to their click depth from root, see
+// nodeDepths. Nodes not reachable from root are left at 0.
+func setClickDepths(nodes []Node, edges []Edge, root string) {
+	depths := nodeDepths(nodes, edges, root)
+	for i := range nodes {
+		nodes[i].Depth = uint(depths[nodes[i].URL])
+	}
+}
+
+// DepthOptions control the click depth report of NewDepthReport.
+type DepthOptions struct {
+	// MaxDepth if not 0, is the depth beyond which pages are
+	// reported as hard to reach.
+	MaxDepth int
+	// Known are urls of the site from elsewhere, a sitemap or a
+	// seed list, those no crawled page links to are reported as orphans.
+	Known []string
+}
+
+type depthPrinter struct {
+	graph   *graph
+	out     io.Writer
+	options DepthOptions
+}
+
+// NewDepthReport returns a GraphProcessor which writes a report of the
+// click depth of pages from the root url to out: the number of pages
+// at every depth, the pages deeper than options.MaxDepth and the
+// orphans among options.Known.
+func NewDepthReport(out io.Writer, options DepthOptions) wire.GraphProcessor {
+	return &depthPrinter{graph: newGraph(), out: out, options: options}
+}
+
+func (depth *depthPrinter) ProcessPage(iPage *wire.Page) error {
+	depth.graph.addPage(iPage)
+	return nil
+}
+
+func (depth *depthPrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, edges := depth.graph.sorted()
+	depths := ClickDepths(nodes, edges, info.RootURL)
+
+	histogram := make(map[int]int)
+	maxDepth := 0
+	var deep []string
+	for _, node := range nodes {
+		nodeDepth, reached := depths[node.URL]
+		if !reached {
+			continue
+		}
+		histogram[nodeDepth]++
+		if nodeDepth > maxDepth {
+			maxDepth = nodeDepth
+		}
+		if depth.options.MaxDepth > 0 && nodeDepth > depth.options.MaxDepth {
+			deep = append(deep, node.URL)
+		}
+	}
+	sort.SliceStable(deep, func(i, j int) bool {
+		return depths[deep[i]] > depths[deep[j]]
+	})
+
+	// Linked to from a page other than itself.
+	linked := make(map[string]bool)
+	for _, edge := range edges {
+		if edge.Kind == EdgeLink && edge.Source != edge.Target {
+			linked[edge.Target] = true
+		}
+	}
+	var orphans []string
+	for _, known := range depth.options.Known {
+		if known != info.RootURL && !linked[known] {
+			orphans = append(orphans, known)
+		}
+	}
+	sort.Strings(orphans)
+
+	buf := bufio.NewWriter(depth.out)
+	fmt.Fprintf(buf, "Click depth from %s\n", info.RootURL)
+	fmt.Fprintf(buf, "%-8s%s\n", "depth", "pages")
+	for nodeDepth := 0; nodeDepth <= maxDepth && len(depths) > 0; nodeDepth++ {
+		fmt.Fprintf(buf, "%-8d%d\n", nodeDepth, histogram[nodeDepth])
+	}
+	if depth.options.MaxDepth > 0 {
+		fmt.Fprintf(buf, "\nPages deeper than %d clicks (%d)\n", depth.options.MaxDepth, len(deep))
+		for _, link := range deep {
+			fmt.Fprintf(buf, "%-8d%s\n", depths[link], link)
+		}
+	}
+	if len(depth.options.Known) > 0 {
+		fmt.Fprintf(buf, "\nOrphan pages, of %d known (%d)\n", len(depth.options.Known), len(orphans))
+		for _, link := range orphans {
+			fmt.Fprintln(buf, link)
+		}
+	}
+	return buf.Flush()
+}
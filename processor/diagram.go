@@ -0,0 +1,201 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiagramOptions control the Mermaid and PlantUML diagrams,
+// meant to be small enough to embed in documents.
+type DiagramOptions struct {
+	// NoAssets leaves out the static assets.
+	NoAssets bool
+	// MaxNodes if not 0, is the most nodes drawn, the ones fewest
+	// clicks from the root are kept and the rest collapsed into one node.
+	MaxNodes int
+}
+
+// The diagram after the node cap, ready to be written.
+type diagramNode struct {
+	id    string
+	label string
+	asset bool
+}
+
+type diagramEdge struct {
+	source, target string
+	label          string
+	asset          bool
+}
+
+type diagramPrinter struct {
+	graph   *graph
+	options DiagramOptions
+	out     io.Writer
+	write   func(*bufio.Writer, []diagramNode, []diagramEdge)
+}
+
+// NewMermaid returns a GraphProcessor which writes the graph
+// as a Mermaid flowchart (graph LR) to out.
+func NewMermaid(out io.Writer, options DiagramOptions) wire.GraphProcessor {
+	return &diagramPrinter{graph: newGraph(), options: options, out: out, write: writeMermaid}
+}
+
+// NewPlantUML returns a GraphProcessor which writes the graph
+// as a PlantUML diagram to out.
+func NewPlantUML(out io.Writer, options DiagramOptions) wire.GraphProcessor {
+	return &diagramPrinter{graph: newGraph(), options: options, out: out, write: writePlantUML}
+}
+
+func (diag *diagramPrinter) ProcessPage(iPage *wire.Page) error {
+	diag.graph.addPage(iPage)
+	return nil
+}
+
+// Shortens a URL to its path, the host is the same for all pages.
+func pathLabel(link string) string {
+	parsedURL, err := url.Parse(link)
+	if err != nil || parsedURL.Path == "" {
+		return "/"
+	}
+	return parsedURL.Path
+}
+
+// Picks the nodes to draw, by click depth from root, and redirects
+// edges of the collapsed ones to a single "… N more" node.
+func (diag *diagramPrinter) layout(root string) ([]diagramNode, []diagramEdge) {
+	nodes, edges := diag.graph.sorted()
+	depths := nodeDepths(nodes, edges, root)
+	depth := func(node Node) int {
+		if depth, reached := depths[node.URL]; reached {
+			return depth
+		}
+		return math.MaxInt32
+	}
+	if diag.options.NoAssets {
+		pages := nodes[:0]
+		for _, node := range nodes {
+			if node.Type != NodeAsset {
+				pages = append(pages, node)
+			}
+		}
+		nodes = pages
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return depth(nodes[i]) < depth(nodes[j])
+	})
+
+	var collapsed int
+	if diag.options.MaxNodes > 0 && len(nodes) > diag.options.MaxNodes {
+		collapsed = len(nodes) - diag.options.MaxNodes
+		nodes = nodes[:diag.options.MaxNodes]
+	}
+	const moreID = "more"
+
+	ids := make(map[string]string, len(nodes))
+	dNodes := make([]diagramNode, 0, len(nodes)+1)
+	for i, node := range nodes {
+		ids[node.URL] = fmt.Sprintf("n%d", i)
+		dNodes = append(dNodes, diagramNode{id: ids[node.URL], label: pathLabel(node.URL), asset: node.Type == NodeAsset})
+	}
+	if collapsed > 0 {
+		dNodes = append(dNodes, diagramNode{id: moreID, label: fmt.Sprintf("… %d more", collapsed)})
+	}
+
+	var dEdges []diagramEdge
+	toMore := make(map[string]bool)
+	for _, edge := range edges {
+		if diag.options.NoAssets && edge.Kind == EdgeAsset {
+			continue
+		}
+		source, exists := ids[edge.Source]
+		if !exists {
+			continue
+		}
+		target, exists := ids[edge.Target]
+		if !exists {
+			if collapsed == 0 || toMore[source] {
+				continue
+			}
+			toMore[source] = true
+			dEdges = append(dEdges, diagramEdge{source: source, target: moreID})
+			continue
+		}
+		dEdge := diagramEdge{source: source, target: target, asset: edge.Kind == EdgeAsset}
+		if !dEdge.asset {
+			dEdge.label = strconv.Itoa(int(edge.Card))
+		}
+		dEdges = append(dEdges, dEdge)
+	}
+	return dNodes, dEdges
+}
+
+func (diag *diagramPrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, edges := diag.layout(info.RootURL)
+	buf := bufio.NewWriter(diag.out)
+	diag.write(buf, nodes, edges)
+	return buf.Flush()
+}
+
+func writeMermaid(out *bufio.Writer, nodes []diagramNode, edges []diagramEdge) {
+	quote := strings.NewReplacer(`"`, "#quot;")
+	var assets []string
+
+	fmt.Fprintln(out, "graph LR")
+	for _, node := range nodes {
+		fmt.Fprintf(out, "  %s[\"%s\"]\n", node.id, quote.Replace(node.label))
+		if node.asset {
+			assets = append(assets, node.id)
+		}
+	}
+	for _, edge := range edges {
+		switch {
+		case edge.asset:
+			fmt.Fprintf(out, "  %s -.-> %s\n", edge.source, edge.target)
+		case edge.label != "":
+			fmt.Fprintf(out, "  %s -->|%s| %s\n", edge.source, edge.label, edge.target)
+		default:
+			fmt.Fprintf(out, "  %s --> %s\n", edge.source, edge.target)
+		}
+	}
+	if len(assets) > 0 {
+		fmt.Fprintln(out, "  classDef asset stroke-dasharray: 5 5")
+		fmt.Fprintf(out, "  class %s asset\n", strings.Join(assets, ","))
+	}
+}
+
+func writePlantUML(out *bufio.Writer, nodes []diagramNode, edges []diagramEdge) {
+	quote := strings.NewReplacer(`"`, `'`)
+
+	fmt.Fprintln(out, "@startuml")
+	fmt.Fprintln(out, "left to right direction")
+	for _, node := range nodes {
+		kind := "rectangle"
+		if node.asset {
+			kind = "file"
+		}
+		fmt.Fprintf(out, "%s \"%s\" as %s\n", kind, quote.Replace(node.label), node.id)
+	}
+	for _, edge := range edges {
+		switch {
+		case edge.asset:
+			fmt.Fprintf(out, "%s ..> %s\n", edge.source, edge.target)
+		case edge.label != "":
+			fmt.Fprintf(out, "%s --> %s : %s\n", edge.source, edge.target, edge.label)
+		default:
+			fmt.Fprintf(out, "%s --> %s\n", edge.source, edge.target)
+		}
+	}
+	fmt.Fprintln(out, "@enduml")
+}
@@ -0,0 +1,258 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	"github.com/awalterschulze/gographviz"
+
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// StatusChange is a page crawled by both crawls with a different status.
+type StatusChange struct {
+	URL       string
+	OldStatus int
+	NewStatus int
+}
+
+// CrawlDiff is what changed from one crawl to another,
+// every list in sort order.
+type CrawlDiff struct {
+	AddedPages    []string
+	RemovedPages  []string
+	StatusChanges []StatusChange
+	// Links between pages.
+	AddedLinks   []Edge
+	RemovedLinks []Edge
+	// Links from pages to static assets.
+	AddedAssets   []Edge
+	RemovedAssets []Edge
+}
+
+// Empty is true if nothing changed.
+func (diff *CrawlDiff) Empty() bool {
+	return len(diff.AddedPages) == 0 && len(diff.RemovedPages) == 0 && len(diff.StatusChanges) == 0 &&
+		len(diff.AddedLinks) == 0 && len(diff.RemovedLinks) == 0 &&
+		len(diff.AddedAssets) == 0 && len(diff.RemovedAssets) == 0
+}
+
+type diffKey struct {
+	source, target, kind string
+}
+
+// Diff compares the crawl in newDoc to the one before it in oldDoc.
+// Statuses are only compared if both have them, graphviz graphs do not.
+func Diff(oldDoc, newDoc *JSONDocument) *CrawlDiff {
+	diff := new(CrawlDiff)
+
+	oldNodes := make(map[string]Node, len(oldDoc.Nodes))
+	for _, node := range oldDoc.Nodes {
+		oldNodes[node.URL] = node
+	}
+	newNodes := make(map[string]Node, len(newDoc.Nodes))
+	for _, node := range newDoc.Nodes {
+		newNodes[node.URL] = node
+		oldNode, exists := oldNodes[node.URL]
+		if node.Type != NodePage {
+			continue
+		}
+		if !exists || oldNode.Type != NodePage {
+			diff.AddedPages = append(diff.AddedPages, node.URL)
+		} else if oldNode.Status != 0 && node.Status != 0 && oldNode.Status != node.Status {
+			diff.StatusChanges = append(diff.StatusChanges, StatusChange{node.URL, oldNode.Status, node.Status})
+		}
+	}
+	for _, node := range oldDoc.Nodes {
+		if newNode, exists := newNodes[node.URL]; node.Type == NodePage && (!exists || newNode.Type != NodePage) {
+			diff.RemovedPages = append(diff.RemovedPages, node.URL)
+		}
+	}
+
+	oldEdges := make(map[diffKey]bool, len(oldDoc.Edges))
+	for _, edge := range oldDoc.Edges {
+		oldEdges[diffKey{edge.Source, edge.Target, edge.Kind}] = true
+	}
+	newEdges := make(map[diffKey]bool, len(newDoc.Edges))
+	for _, edge := range newDoc.Edges {
+		newEdges[diffKey{edge.Source, edge.Target, edge.Kind}] = true
+		if oldEdges[diffKey{edge.Source, edge.Target, edge.Kind}] {
+			continue
+		}
+		if edge.Kind == EdgeAsset {
+			diff.AddedAssets = append(diff.AddedAssets, edge)
+		} else {
+			diff.AddedLinks = append(diff.AddedLinks, edge)
+		}
+	}
+	for _, edge := range oldDoc.Edges {
+		if newEdges[diffKey{edge.Source, edge.Target, edge.Kind}] {
+			continue
+		}
+		if edge.Kind == EdgeAsset {
+			diff.RemovedAssets = append(diff.RemovedAssets, edge)
+		} else {
+			diff.RemovedLinks = append(diff.RemovedLinks, edge)
+		}
+	}
+
+	sort.Strings(diff.AddedPages)
+	sort.Strings(diff.RemovedPages)
+	sort.Slice(diff.StatusChanges, func(i, j int) bool {
+		return diff.StatusChanges[i].URL < diff.StatusChanges[j].URL
+	})
+	for _, edges := range [][]Edge{diff.AddedLinks, diff.RemovedLinks, diff.AddedAssets, diff.RemovedAssets} {
+		sortEdges(edges)
+	}
+	return diff
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+}
+
+// WriteReport writes the changes as text to out, a section
+// for every kind of change, + for added and - for removed.
+func (diff *CrawlDiff) WriteReport(out io.Writer) error {
+	buf := bufio.NewWriter(out)
+	section := func(title string, count int) {
+		if count > 0 {
+			fmt.Fprintf(buf, "%s (%d)\n", title, count)
+		}
+	}
+	writeEdges := func(sign string, edges []Edge) {
+		for _, edge := range edges {
+			fmt.Fprintf(buf, "%s %s -> %s\n", sign, edge.Source, edge.Target)
+		}
+	}
+
+	section("Pages", len(diff.AddedPages)+len(diff.RemovedPages))
+	for _, link := range diff.AddedPages {
+		fmt.Fprintf(buf, "+ %s\n", link)
+	}
+	for _, link := range diff.RemovedPages {
+		fmt.Fprintf(buf, "- %s\n", link)
+	}
+	section("Status", len(diff.StatusChanges))
+	for _, change := range diff.StatusChanges {
+		fmt.Fprintf(buf, "  %s %d -> %d\n", change.URL, change.OldStatus, change.NewStatus)
+	}
+	section("Links", len(diff.AddedLinks)+len(diff.RemovedLinks))
+	writeEdges("+", diff.AddedLinks)
+	writeEdges("-", diff.RemovedLinks)
+	section("Assets", len(diff.AddedAssets)+len(diff.RemovedAssets))
+	writeEdges("+", diff.AddedAssets)
+	writeEdges("-", diff.RemovedAssets)
+	return buf.Flush()
+}
+
+// Colors of the graph of a diff.
+const (
+	diffAdded   = "green"
+	diffRemoved = "red"
+	diffStatus  = "orange"
+)
+
+// WriteDot writes to out the graphviz graph of both crawls, pages
+// and links added by newDoc in green and those removed in red.
+// Pages whose status changed are orange.
+func (diff *CrawlDiff) WriteDot(out io.Writer, oldDoc, newDoc *JSONDocument) error {
+	cgraph := gographviz.NewEscape()
+	cgraph.SetName("dotler")
+	cgraph.SetDir(true)
+	cgraph.SetStrict(true)
+
+	// Static assets are colored too, by which crawl has them.
+	colors := make(map[string]string)
+	tooltips := make(map[string]string)
+	for _, node := range newDoc.Nodes {
+		colors[node.URL] = diffAdded
+	}
+	for _, node := range oldDoc.Nodes {
+		if _, exists := colors[node.URL]; exists {
+			colors[node.URL] = ""
+		} else {
+			colors[node.URL] = diffRemoved
+		}
+	}
+	for _, change := range diff.StatusChanges {
+		colors[change.URL] = diffStatus
+		tooltips[change.URL] = fmt.Sprintf("%d -> %d", change.OldStatus, change.NewStatus)
+	}
+	edgeColors := make(map[diffKey]string)
+	for color, edges := range map[string][][]Edge{
+		diffAdded:   {diff.AddedLinks, diff.AddedAssets},
+		diffRemoved: {diff.RemovedLinks, diff.RemovedAssets},
+	} {
+		for _, kind := range edges {
+			for _, edge := range kind {
+				edgeColors[diffKey{edge.Source, edge.Target, edge.Kind}] = color
+			}
+		}
+	}
+
+	// Removed nodes and edges are only in oldDoc.
+	nodes := make(map[string]Node)
+	var edges []Edge
+	seen := make(map[diffKey]bool)
+	for _, doc := range []*JSONDocument{newDoc, oldDoc} {
+		for _, node := range doc.Nodes {
+			if _, exists := nodes[node.URL]; !exists {
+				nodes[node.URL] = node
+			}
+		}
+		for _, edge := range doc.Edges {
+			if key := (diffKey{edge.Source, edge.Target, edge.Kind}); !seen[key] {
+				seen[key] = true
+				edges = append(edges, edge)
+			}
+		}
+	}
+	links := make([]string, 0, len(nodes))
+	for link := range nodes {
+		links = append(links, link)
+	}
+	sort.Strings(links)
+	sortEdges(edges)
+
+	for _, link := range links {
+		quotedURL := strconv.Quote(link)
+		attrs := map[string]string{"URL": quotedURL}
+		if nodes[link].Type == NodeAsset {
+			attrs["style"] = "dashed"
+		}
+		if color := colors[link]; color != "" {
+			attrs["color"] = color
+			attrs["fontcolor"] = color
+		}
+		if tooltip := tooltips[link]; tooltip != "" {
+			attrs["tooltip"] = strconv.Quote(tooltip)
+		}
+		cgraph.AddNode("dotler", quotedURL, attrs)
+	}
+	for _, edge := range edges {
+		attrs := make(map[string]string)
+		if edge.Kind == EdgeAsset {
+			attrs["style"] = "dashed"
+			attrs["color"] = "blue"
+		} else {
+			attrs["label"] = strconv.Itoa(int(edge.Card))
+		}
+		if color := edgeColors[diffKey{edge.Source, edge.Target, edge.Kind}]; color != "" {
+			attrs["color"] = color
+			attrs["fontcolor"] = color
+		}
+		cgraph.AddEdge(strconv.Quote(edge.Source), strconv.Quote(edge.Target), true, attrs)
+	}
+	_, err := io.WriteString(out, cgraph.String())
+	return err
+}
@@ -0,0 +1,181 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// NearDuplicateBits is the most bits the SimHash of the text of two
+// pages may differ by for them to be near duplicates.
+const NearDuplicateBits = 3
+
+// DuplicateGroup is a group of pages with the same content, under
+// different urls. Exact if their bodies are identical, else the SimHash
+// of the text of every page is at most NearDuplicateBits from another
+// of the group.
+type DuplicateGroup struct {
+	Exact bool
+	URLs  []string
+}
+
+// DuplicateGroups returns the groups of duplicate pages among the
+// successfully crawled (2xx) nodes, exact ones first, each ordered
+// by its first url.
+// Pages of an exact group are in the near group around it too,
+// if there are other pages near it.
+func DuplicateGroups(nodes []Node) []DuplicateGroup {
+	var pages []Node
+	for _, node := range nodes {
+		if node.Type == NodePage && node.ContentHash != "" && node.Status >= 200 && node.Status <= 299 {
+			pages = append(pages, node)
+		}
+	}
+	sort.Slice(pages, func(i, j int) bool {
+		return pages[i].URL < pages[j].URL
+	})
+
+	// Union find over both equal bodies and near SimHash.
+	parent := make([]int, len(pages))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	exact := make(map[string][]string)
+	firstExact := make(map[string]int)
+	for i, page := range pages {
+		if first, exists := firstExact[page.ContentHash]; exists {
+			parent[find(i)] = find(first)
+		} else {
+			firstExact[page.ContentHash] = i
+		}
+		exact[page.ContentHash] = append(exact[page.ContentHash], page.URL)
+	}
+
+	// Near pages differ in at most NearDuplicateBits bits, so they have
+	// the same bits in one of NearDuplicateBits+1 bands of their SimHash
+	// at least. Only pages sharing a band are compared, which is still
+	// quadratic for the pages of a band, if most of the site has it.
+	const bands = NearDuplicateBits + 1
+	const bandBits = 64 / bands
+	hashes := make([]uint64, len(pages))
+	for i, page := range pages {
+		hashes[i], _ = strconv.ParseUint(page.SimHash, 16, 64)
+	}
+	buckets := make(map[[2]uint64][]int)
+	for i, hash := range hashes {
+		if hash == 0 {
+			continue
+		}
+		for band := uint64(0); band < bands; band++ {
+			bucket := [2]uint64{band, hash >> (band * bandBits) & (1<<bandBits - 1)}
+			for _, j := range buckets[bucket] {
+				if bits.OnesCount64(hash^hashes[j]) <= NearDuplicateBits {
+					parent[find(i)] = find(j)
+				}
+			}
+			buckets[bucket] = append(buckets[bucket], i)
+		}
+	}
+
+	var exactGroups, nearGroups []DuplicateGroup
+	for _, links := range exact {
+		if len(links) > 1 {
+			exactGroups = append(exactGroups, DuplicateGroup{Exact: true, URLs: links})
+		}
+	}
+	components := make(map[int][]string)
+	for i, page := range pages {
+		components[find(i)] = append(components[find(i)], page.URL)
+	}
+	for root, links := range components {
+		if len(links) > 1 && len(exact[pages[root].ContentHash]) != len(links) {
+			nearGroups = append(nearGroups, DuplicateGroup{URLs: links})
+		}
+	}
+	for _, groups := range [][]DuplicateGroup{exactGroups, nearGroups} {
+		sort.Slice(groups, func(i, j int) bool {
+			return groups[i].URLs[0] < groups[j].URLs[0]
+		})
+	}
+	return append(exactGroups, nearGroups...)
+}
+
+type duplicatePrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewDuplicateReport returns a GraphProcessor which writes the groups
+// of duplicate pages, see DuplicateGroups, to out, the pages of a group
+// after its number.
+func NewDuplicateReport(out io.Writer) wire.GraphProcessor {
+	return &duplicatePrinter{graph: newGraph(), out: out}
+}
+
+func (dup *duplicatePrinter) ProcessPage(iPage *wire.Page) error {
+	dup.graph.addPage(iPage)
+	return nil
+}
+
+func (dup *duplicatePrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, _ := dup.graph.sorted()
+	groups := DuplicateGroups(nodes)
+	exact := 0
+	for exact < len(groups) && groups[exact].Exact {
+		exact++
+	}
+
+	buf := bufio.NewWriter(dup.out)
+	fmt.Fprintf(buf, "Exact duplicates (%d)\n", exact)
+	for i, group := range groups[:exact] {
+		for _, link := range group.URLs {
+			fmt.Fprintf(buf, "%-4d%s\n", i+1, link)
+		}
+	}
+	fmt.Fprintf(buf, "\nNear duplicates, up to %d bits apart (%d)\n", NearDuplicateBits, len(groups)-exact)
+	for i, group := range groups[exact:] {
+		for _, link := range group.URLs {
+			fmt.Fprintf(buf, "%-4d%s\n", exact+i+1, link)
+		}
+	}
+	return buf.Flush()
+}
+
+// Pages in the same group of duplicates get the same color of these.
+var duplicateColors = []string{"red", "blue", "green", "orange", "purple", "brown", "magenta", "cyan"}
+
+// Outlines duplicate pages in the color of their group, near groups
+// over exact ones since they have the same pages and more.
+func (dot *dotPrinter) addDuplicates() error {
+	nodes, _ := dot.graph.sorted()
+	for i, group := range DuplicateGroups(nodes) {
+		color := duplicateColors[i%len(duplicateColors)]
+		for _, link := range group.URLs {
+			pageURL, err := url.Parse(link)
+			if err != nil {
+				return err
+			}
+			dot.addNode(fmt.Sprintf("%q", link), pageURL, false, map[string]string{
+				"color":    color,
+				"penwidth": "3",
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// ExternalLink is a link of a page to another host,
+// Card is the number of such links on the page.
+type ExternalLink struct {
+	URL  string `json:"url"`
+	Card uint   `json:"card"`
+}
+
+// Returns the external links of a page sorted by url.
+func externalLinks(links map[string]uint) []ExternalLink {
+	var external []ExternalLink
+	for link, card := range links {
+		external = append(external, ExternalLink{URL: link, Card: card})
+	}
+	sort.Slice(external, func(i, j int) bool {
+		return external[i].URL < external[j].URL
+	})
+	return external
+}
+
+// ExternalCheck is the result of checking an external link, see wire.LinkCheck.
+type ExternalCheck struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Broken is if the request failed or got a 4xx or 5xx status.
+func (check ExternalCheck) Broken() bool {
+	return check.Error != "" || check.Status >= 400
+}
+
+// ExternalChecks returns checks sorted by url.
+func ExternalChecks(checks map[string]wire.LinkCheck) []ExternalCheck {
+	var sorted []ExternalCheck
+	for link, check := range checks {
+		sorted = append(sorted, ExternalCheck{URL: link, Status: check.Status, Error: check.Error})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].URL < sorted[j].URL
+	})
+	return sorted
+}
+
+// LinkChecks returns the checks of external links of the document
+// by url, nil if they were not checked.
+func (doc *JSONDocument) LinkChecks() map[string]wire.LinkCheck {
+	if doc.ExternalChecks == nil {
+		return nil
+	}
+	checks := make(map[string]wire.LinkCheck, len(doc.ExternalChecks))
+	for _, check := range doc.ExternalChecks {
+		checks[check.URL] = wire.LinkCheck{Status: check.Status, Error: check.Error}
+	}
+	return checks
+}
+
+// Returns the host of an external link, the link itself if it has none.
+func externalHost(link string) string {
+	if linkURL, err := url.Parse(link); err == nil && linkURL.Host != "" {
+		return linkURL.Host
+	}
+	return link
+}
+
+type externalPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewExternalReport returns a GraphProcessor which writes the hosts
+// crawled pages link to, with the number of links to each, and the
+// broken external links with the pages they are on to out.
+// Broken links are only known if they were checked, see wire.CrawlInfo.
+func NewExternalReport(out io.Writer) wire.GraphProcessor {
+	return &externalPrinter{graph: newGraph(), out: out}
+}
+
+func (ext *externalPrinter) ProcessPage(iPage *wire.Page) error {
+	ext.graph.addPage(iPage)
+	return nil
+}
+
+func (ext *externalPrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, _ := ext.graph.sorted()
+	hostLinks := make(map[string]uint)
+	// Pages with every external link, in order of url.
+	sources := make(map[string][]string)
+	for _, node := range nodes {
+		for _, link := range node.External {
+			hostLinks[externalHost(link.URL)] += link.Card
+			sources[link.URL] = append(sources[link.URL], node.URL)
+		}
+	}
+	var hosts []string
+	for host := range hostLinks {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hostLinks[hosts[i]] != hostLinks[hosts[j]] {
+			return hostLinks[hosts[i]] > hostLinks[hosts[j]]
+		}
+		return hosts[i] < hosts[j]
+	})
+
+	buf := bufio.NewWriter(ext.out)
+	fmt.Fprintf(buf, "External hosts (%d)\n", len(hosts))
+	for _, host := range hosts {
+		fmt.Fprintf(buf, "%-8d%s\n", hostLinks[host], host)
+	}
+	if info.External == nil {
+		fmt.Fprintln(buf, "\nExternal links were not checked")
+		return buf.Flush()
+	}
+
+	var broken []ExternalCheck
+	for _, check := range ExternalChecks(info.External) {
+		if check.Broken() && len(sources[check.URL]) > 0 {
+			broken = append(broken, check)
+		}
+	}
+	fmt.Fprintf(buf, "\nBroken external links (%d)\n", len(broken))
+	for _, check := range broken {
+		status := "error"
+		if check.Status != 0 {
+			status = strconv.Itoa(check.Status)
+		}
+		for _, source := range sources[check.URL] {
+			fmt.Fprintf(buf, "%-8s%s on %s", status, check.URL, source)
+			if check.Error != "" {
+				fmt.Fprintf(buf, ": %s", check.Error)
+			}
+			fmt.Fprintln(buf)
+		}
+	}
+	return buf.Flush()
+}
+
+// Adds a node per host with external links of the page and a link,
+// labelled with the number of them, to it. Hosts are boxes named by
+// their host, unlike pages which are named by their url.
+func (dot *dotPrinter) addExternal(presURL string, iPage *wire.Page) {
+	hostLinks := make(map[string]uint)
+	for link, card := range iPage.External {
+		hostLinks[externalHost(link)] += card
+	}
+	for host, card := range hostLinks {
+		quotedHost := fmt.Sprintf("%q", host)
+		dot.addNode(quotedHost, &url.URL{Host: host, Path: "/"}, true, map[string]string{
+			"shape": "box",
+			"style": "rounded",
+		})
+		dot.addEdge(presURL, quotedHost, card, map[string]string{
+			"label": strconv.Itoa(int(card)),
+			"style": "dotted",
+		})
+	}
+	for link := range iPage.External {
+		dot.external[link] = true
+	}
+}
+
+// Colors hosts with broken external links red.
+func (dot *dotPrinter) addBrokenExternal(checks map[string]wire.LinkCheck) {
+	for _, check := range ExternalChecks(checks) {
+		if check.Broken() && dot.external[check.URL] {
+			quotedHost := fmt.Sprintf("%q", externalHost(check.URL))
+			dot.addNode(quotedHost, &url.URL{Host: externalHost(check.URL), Path: "/"}, true, map[string]string{
+				"color":     "red",
+				"fontcolor": "red",
+			})
+		}
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	"github.com/golang/glog"
+	wire "github.com/ronin13/dotler/wire"
+
+	"sort"
+)
+
+// FanOut feeds every crawled page to a set of GraphProcessors.
+// Each processor is known by its name, errors are reported per name.
+type FanOut struct {
+	names  []string
+	procs  map[string]wire.GraphProcessor
+	errs   map[string]error
+	spill  wire.Spiller
+	done   chan struct{}
+	assets AssetOptions
+	// With AssetsShared, pages are fed at Finish and refs counts
+	// the pages referencing every asset till then. Spilled pages
+	// are kept by url in keys and loaded back, the rest are held.
+	keys []string
+	held []*wire.Page
+	refs map[string]int
+}
+
+// NewFanOut returns a FanOut for procs, the assets of every page
+// are handed to them as per assets.
+// If spill is not nil, each page is spilled once all
+// the processors are done with it.
+func NewFanOut(procs map[string]wire.GraphProcessor, spill wire.Spiller, assets AssetOptions) *FanOut {
+	fan := &FanOut{
+		procs:  procs,
+		errs:   make(map[string]error),
+		spill:  spill,
+		done:   make(chan struct{}),
+		assets: assets,
+		refs:   make(map[string]int),
+	}
+	for name := range procs {
+		fan.names = append(fan.names, name)
+	}
+	sort.Strings(fan.names)
+	return fan
+}
+
+// Processors which failed are not fed any more pages.
+func (fan *FanOut) feed(iPage *wire.Page) {
+	for _, name := range fan.names {
+		if fan.errs[name] != nil {
+			continue
+		}
+		if err := fan.procs[name].ProcessPage(iPage); err != nil {
+			glog.Errorf("Processor %s failed on %s: %s", name, iPage.PageURL.String(), err)
+			fan.errs[name] = err
+		}
+	}
+}
+
+func (fan *FanOut) process(iPage *wire.Page) {
+	if fan.assets.Mode == AssetsShared {
+		for key := range iPage.StatList {
+			fan.refs[key]++
+		}
+		if fan.spill != nil {
+			fan.keys = append(fan.keys, iPage.PageURL.String())
+		} else {
+			fan.held = append(fan.held, iPage)
+		}
+	} else {
+		fan.feed(fan.assets.filter(iPage, nil))
+	}
+
+	if fan.spill != nil {
+		if err := fan.spill.Spill(iPage.PageURL.String()); err != nil {
+			glog.Errorf("Failed to spill %s: %s", iPage.PageURL.String(), err)
+		}
+	}
+}
+
+// Finish waits for ProcessLoop to be done with all the pages
+// and asks every processor to write its output.
+// Returns the errors of failed processors, keyed by their name.
+func (fan *FanOut) Finish(info *wire.CrawlInfo) map[string]error {
+	<-fan.done
+	for _, iPage := range fan.held {
+		fan.feed(fan.assets.filter(iPage, fan.refs))
+	}
+	for _, key := range fan.keys {
+		iPage, err := fan.spill.Load(key)
+		if err != nil {
+			glog.Errorf("Failed to load %s: %s", key, err)
+			continue
+		}
+		fan.feed(fan.assets.filter(iPage, fan.refs))
+	}
+	fan.held, fan.keys = nil, nil
+
+	for _, name := range fan.names {
+		if fan.errs[name] != nil {
+			continue
+		}
+		if err := fan.procs[name].Finish(info); err != nil {
+			glog.Errorf("Processor %s failed to finish: %s", name, err)
+			fan.errs[name] = err
+		}
+	}
+	return fan.errs
+}
+
+// ProcessLoop runs till inChan is closed, during shutdown.
+// Runs in parallel with crawler, silently weaving the graph
+// in background.
+func (fan *FanOut) ProcessLoop(inChan chan *wire.Page) {
+	glog.Infof("Starting the processors %v!", fan.names)
+	go func() {
+		for iPage := range inChan {
+			if iPage != nil {
+				fan.process(iPage)
+			}
+		}
+		glog.Infoln("Halting the processors!")
+		close(fan.done)
+	}()
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BrokenFragment is a link to a #fragment which is not an anchor,
+// an id or a name of <a>, of the page it points to.
+// Count is the number of such links from Source.
+type BrokenFragment struct {
+	Source   string
+	Target   string
+	Fragment string
+	Count    int
+}
+
+// Fragments which are fine without an anchor: the top of the page,
+// hashbang routes of scripts and text fragments.
+func ignoredFragment(fragment string) bool {
+	return fragment == "" || strings.EqualFold(fragment, "top") ||
+		strings.HasPrefix(fragment, "!") || strings.HasPrefix(fragment, ":~:")
+}
+
+// BrokenFragments returns the links in edges to fragments missing from
+// the pages they point to, only for crawled pages with a 2xx status,
+// sorted by source, target and fragment.
+func BrokenFragments(nodes []Node, edges []Edge) []BrokenFragment {
+	anchors := make(map[string]map[string]bool)
+	for _, node := range nodes {
+		if node.Type != NodePage || node.Status < 200 || node.Status >= 300 {
+			continue
+		}
+		anchors[node.URL] = make(map[string]bool, len(node.Anchors))
+		for _, anchor := range node.Anchors {
+			anchors[node.URL][anchor] = true
+		}
+	}
+
+	var broken []BrokenFragment
+	for _, edge := range edges {
+		targetAnchors, crawled := anchors[edge.Target]
+		if edge.Kind != EdgeLink || !crawled {
+			continue
+		}
+		counts := make(map[string]int)
+		for _, link := range edge.Links {
+			if !ignoredFragment(link.Fragment) && !targetAnchors[link.Fragment] {
+				counts[link.Fragment]++
+			}
+		}
+		for fragment, count := range counts {
+			broken = append(broken, BrokenFragment{Source: edge.Source, Target: edge.Target, Fragment: fragment, Count: count})
+		}
+	}
+	sort.Slice(broken, func(i, j int) bool {
+		if broken[i].Source != broken[j].Source {
+			return broken[i].Source < broken[j].Source
+		}
+		if broken[i].Target != broken[j].Target {
+			return broken[i].Target < broken[j].Target
+		}
+		return broken[i].Fragment < broken[j].Fragment
+	})
+	return broken
+}
+
+type fragmentPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewFragmentReport returns a GraphProcessor which writes the broken
+// fragment links, see BrokenFragments, to out, one per line with the
+// page they are on and the number of them.
+func NewFragmentReport(out io.Writer) wire.GraphProcessor {
+	return &fragmentPrinter{graph: newGraph(), out: out}
+}
+
+func (frag *fragmentPrinter) ProcessPage(iPage *wire.Page) error {
+	frag.graph.addPage(iPage)
+	return nil
+}
+
+func (frag *fragmentPrinter) Finish(info *wire.CrawlInfo) error {
+	broken := BrokenFragments(frag.graph.sorted())
+	buf := bufio.NewWriter(frag.out)
+	fmt.Fprintf(buf, "Broken fragment links (%d)\n", len(broken))
+	for _, link := range broken {
+		fmt.Fprintf(buf, "%-4d%s#%s on %s\n", link.Count, link.Target, link.Fragment, link.Source)
+	}
+	return buf.Flush()
+}
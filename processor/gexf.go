@@ -0,0 +1,150 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type gexfNode struct {
+	ID     string      `xml:"id,attr"`
+	Label  string      `xml:"label,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string      `xml:"id,attr"`
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Label  string      `xml:"label,attr,omitempty"`
+	Weight uint        `xml:"weight,attr"`
+	Values []gexfValue `xml:"attvalues>attvalue"`
+}
+
+type gexfDocument struct {
+	XMLName xml.Name `xml:"gexf"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	Version string   `xml:"version,attr"`
+	Meta    struct {
+		LastModified string `xml:"lastmodifieddate,attr"`
+		Creator      string `xml:"creator"`
+		Description  string `xml:"description"`
+	} `xml:"meta"`
+	Graph struct {
+		DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+		Mode            string           `xml:"mode,attr"`
+		Attributes      []gexfAttributes `xml:"attributes"`
+		Nodes           []gexfNode       `xml:"nodes>node"`
+		Edges           []gexfEdge       `xml:"edges>edge"`
+	} `xml:"graph"`
+}
+
+// Same attributes as the dot printer.
+var gexfAttributeClasses = []gexfAttributes{
+	{Class: "node", Attributes: []gexfAttribute{
+		{ID: "url", Title: "URL", Type: "string"},
+		{ID: "type", Title: "type", Type: "string"},
+		{ID: "status", Title: "status", Type: "integer"},
+		{ID: "title", Title: "title", Type: "string"},
+		{ID: "description", Title: "description", Type: "string"},
+		{ID: "h1", Title: "h1", Type: "string"},
+		{ID: "lang", Title: "lang", Type: "string"},
+		{ID: "words", Title: "word_count", Type: "integer"},
+		{ID: "style", Title: "style", Type: "string"},
+	}},
+	{Class: "edge", Attributes: []gexfAttribute{
+		{ID: "kind", Title: "kind", Type: "string"},
+		{ID: "anchor", Title: "anchor", Type: "string"},
+		{ID: "style", Title: "style", Type: "string"},
+		{ID: "color", Title: "color", Type: "string"},
+	}},
+}
+
+type gexfPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewGEXF returns a GraphProcessor which writes the graph as GEXF to out.
+func NewGEXF(out io.Writer) wire.GraphProcessor {
+	return &gexfPrinter{graph: newGraph(), out: out}
+}
+
+func (gexf *gexfPrinter) ProcessPage(iPage *wire.Page) error {
+	gexf.graph.addPage(iPage)
+	return nil
+}
+
+func (gexf *gexfPrinter) Finish(info *wire.CrawlInfo) error {
+	doc := gexfDocument{XMLNS: "http://www.gexf.net/1.2draft", Version: "1.2"}
+	doc.Meta.LastModified = info.EndTime.Format("2006-01-02")
+	doc.Meta.Creator = "dotler"
+	doc.Meta.Description = info.RootURL
+	doc.Graph.DefaultEdgeType = "directed"
+	doc.Graph.Mode = "static"
+	doc.Graph.Attributes = gexfAttributeClasses
+
+	nodes, edges := gexf.graph.sorted()
+	ids := make(map[string]string, len(nodes))
+	for i, node := range nodes {
+		ids[node.URL] = fmt.Sprintf("n%d", i)
+		label := node.Title
+		if label == "" {
+			label = node.URL
+		}
+		gNode := gexfNode{ID: ids[node.URL], Label: label, Values: []gexfValue{
+			{For: "url", Value: node.URL},
+			{For: "type", Value: node.Type},
+			{For: "status", Value: strconv.Itoa(node.Status)},
+		}}
+		if node.Type == NodeAsset {
+			gNode.Values = append(gNode.Values, gexfValue{For: "style", Value: "dashed"})
+		} else if node.Status != 0 {
+			gNode.Values = append(gNode.Values,
+				gexfValue{For: "title", Value: node.Title},
+				gexfValue{For: "description", Value: node.Description},
+				gexfValue{For: "h1", Value: node.H1},
+				gexfValue{For: "lang", Value: node.Lang},
+				gexfValue{For: "words", Value: strconv.Itoa(node.WordCount)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gNode)
+	}
+	for i, edge := range edges {
+		gEdge := gexfEdge{ID: fmt.Sprintf("e%d", i), Source: ids[edge.Source], Target: ids[edge.Target], Weight: edge.Card, Values: []gexfValue{
+			{For: "kind", Value: edge.Kind},
+		}}
+		if edge.Kind == EdgeAsset {
+			gEdge.Values = append(gEdge.Values, gexfValue{For: "style", Value: "dashed"}, gexfValue{For: "color", Value: "blue"})
+		} else {
+			gEdge.Label = strconv.Itoa(int(edge.Card))
+			if edge.Anchor != "" {
+				gEdge.Values = append(gEdge.Values, gexfValue{For: "anchor", Value: edge.Anchor})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, gEdge)
+	}
+
+	return writeXML(gexf.out, &doc)
+}
@@ -0,0 +1,171 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"fmt"
+	"sort"
+)
+
+// Node types and edge kinds.
+const (
+	NodePage  = "page"
+	NodeAsset = "asset"
+	EdgeLink  = "link"
+	EdgeAsset = "asset"
+)
+
+// Node is a page or a static asset, as given by Type.
+// Description, H1, Lang, WordCount, ContentHash, SimHash, Anchors,
+// Insecure, Headers, Cookies, External and the fields of the audit, H1Count,
+// MissingAlt, Canonical and NoIndex, are only known for crawled pages,
+// SimHash is in hex. Depth is the click depth from the root url,
+// see ClickDepths, known once the crawl is done.
+type Node struct {
+	URL         string            `json:"url"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	H1          string            `json:"h1,omitempty"`
+	H1Count     int               `json:"h1_count,omitempty"`
+	MissingAlt  int               `json:"missing_alt,omitempty"`
+	Canonical   string            `json:"canonical,omitempty"`
+	NoIndex     bool              `json:"noindex,omitempty"`
+	Anchors     []string          `json:"anchors,omitempty"`
+	Insecure    []InsecureRef     `json:"insecure,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Cookies     []Cookie          `json:"cookies,omitempty"`
+	External    []ExternalLink    `json:"external,omitempty"`
+	Lang        string            `json:"lang,omitempty"`
+	WordCount   int               `json:"word_count,omitempty"`
+	ContentHash string            `json:"content_hash,omitempty"`
+	SimHash     string            `json:"simhash,omitempty"`
+	Status      int               `json:"status,omitempty"`
+	Depth       uint              `json:"depth"`
+	Type        string            `json:"type"`
+}
+
+// Edge is a link from a page to another page or a static asset,
+// as given by Kind. Card is the number of such links.
+// Links to pages have every occurrence in Links and the most
+// common anchor text of them in Anchor.
+type Edge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Card   uint   `json:"card"`
+	Kind   string `json:"kind"`
+	Anchor string `json:"anchor,omitempty"`
+	Links  []Link `json:"links,omitempty"`
+}
+
+// Link is a single occurrence of a link of an Edge, see wire.Link.
+type Link struct {
+	Text     string   `json:"text,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Rel      []string `json:"rel,omitempty"`
+	Region   string   `json:"region,omitempty"`
+	Fragment string   `json:"fragment,omitempty"`
+}
+
+// Returns the edge of a link to a page.
+func linkEdge(source string, oPage *wire.PageWithCard) Edge {
+	edge := Edge{
+		Source: source,
+		Target: oPage.Page.PageURL.String(),
+		Card:   oPage.Card,
+		Kind:   EdgeLink,
+		Anchor: oPage.Anchor(),
+	}
+	for _, link := range oPage.Links {
+		edge.Links = append(edge.Links, Link(link))
+	}
+	return edge
+}
+
+// graph collects the nodes and edges of pages, for the
+// processors which write the whole graph at Finish.
+type graph struct {
+	nodes map[string]Node
+	edges []Edge
+}
+
+func newGraph() *graph {
+	return &graph{nodes: make(map[string]Node)}
+}
+
+func (gr *graph) addPage(iPage *wire.Page) {
+	presURL := iPage.PageURL.String()
+	gr.nodes[presURL] = pageNode(iPage)
+
+	for _, oPage := range iPage.OutLinks {
+		addedURL := oPage.Page.PageURL.String()
+		// Crawled pages fill in the rest when they arrive.
+		if _, exists := gr.nodes[addedURL]; !exists {
+			gr.nodes[addedURL] = Node{URL: addedURL, Type: NodePage}
+		}
+		gr.edges = append(gr.edges, linkEdge(presURL, oPage))
+	}
+
+	for _, sPage := range iPage.StatList {
+		addedURL := sPage.StaticURL.String()
+		if _, exists := gr.nodes[addedURL]; !exists {
+			gr.nodes[addedURL] = Node{URL: addedURL, Title: sPage.PageTitle, Type: NodeAsset}
+		}
+		gr.edges = append(gr.edges, Edge{Source: presURL, Target: addedURL, Card: 1, Kind: EdgeAsset})
+	}
+}
+
+// Returns the node of a crawled page.
+func pageNode(iPage *wire.Page) Node {
+	return Node{
+		URL:         iPage.PageURL.String(),
+		Title:       iPage.Title,
+		Description: iPage.Description,
+		H1:          iPage.H1,
+		H1Count:     iPage.H1Count,
+		MissingAlt:  iPage.MissingAlt,
+		Canonical:   iPage.Canonical,
+		NoIndex:     iPage.NoIndex,
+		Anchors:     iPage.Anchors,
+		Insecure:    insecureRefs(iPage.Insecure),
+		Headers:     iPage.Headers,
+		Cookies:     cookies(iPage.Cookies),
+		External:    externalLinks(iPage.External),
+		Lang:        iPage.Lang,
+		WordCount:   iPage.WordCount,
+		ContentHash: iPage.ContentHash,
+		SimHash:     formatSimHash(iPage.SimHash),
+		Status:      iPage.Status,
+		Type:        NodePage,
+	}
+}
+
+// SimHash of Node, empty for none.
+func formatSimHash(hash uint64) string {
+	if hash == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// Returns nodes sorted by url, edges by source and then target.
+func (gr *graph) sorted() ([]Node, []Edge) {
+	nodes := make([]Node, 0, len(gr.nodes))
+	for _, node := range gr.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].URL < nodes[j].URL
+	})
+
+	edges := append([]Edge{}, gr.edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Target < edges[j].Target
+	})
+	return nodes, edges
+}
@@ -0,0 +1,139 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// Same attributes as the dot printer.
+var graphMLKeys = []graphMLKey{
+	{ID: "url", For: "node", Name: "URL", Type: "string"},
+	{ID: "title", For: "node", Name: "title", Type: "string"},
+	{ID: "type", For: "node", Name: "type", Type: "string"},
+	{ID: "status", For: "node", Name: "status", Type: "int"},
+	{ID: "description", For: "node", Name: "description", Type: "string"},
+	{ID: "h1", For: "node", Name: "h1", Type: "string"},
+	{ID: "lang", For: "node", Name: "lang", Type: "string"},
+	{ID: "words", For: "node", Name: "word_count", Type: "int"},
+	{ID: "nstyle", For: "node", Name: "style", Type: "string"},
+	{ID: "label", For: "edge", Name: "label", Type: "int"},
+	{ID: "kind", For: "edge", Name: "kind", Type: "string"},
+	{ID: "anchor", For: "edge", Name: "anchor", Type: "string"},
+	{ID: "estyle", For: "edge", Name: "style", Type: "string"},
+	{ID: "color", For: "edge", Name: "color", Type: "string"},
+}
+
+type graphMLPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewGraphML returns a GraphProcessor which writes the graph as GraphML to out.
+func NewGraphML(out io.Writer) wire.GraphProcessor {
+	return &graphMLPrinter{graph: newGraph(), out: out}
+}
+
+func (gml *graphMLPrinter) ProcessPage(iPage *wire.Page) error {
+	gml.graph.addPage(iPage)
+	return nil
+}
+
+func (gml *graphMLPrinter) Finish(info *wire.CrawlInfo) error {
+	doc := graphMLDocument{XMLNS: "http://graphml.graphdrawing.org/xmlns", Keys: graphMLKeys}
+	doc.Graph.ID = "dotler"
+	doc.Graph.EdgeDefault = "directed"
+
+	nodes, edges := gml.graph.sorted()
+	ids := make(map[string]string, len(nodes))
+	for i, node := range nodes {
+		ids[node.URL] = fmt.Sprintf("n%d", i)
+		gNode := graphMLNode{ID: ids[node.URL], Data: []graphMLData{
+			{Key: "url", Value: node.URL},
+			{Key: "title", Value: node.Title},
+			{Key: "type", Value: node.Type},
+			{Key: "status", Value: strconv.Itoa(node.Status)},
+		}}
+		if node.Type == NodeAsset {
+			gNode.Data = append(gNode.Data, graphMLData{Key: "nstyle", Value: "dashed"})
+		} else if node.Status != 0 {
+			gNode.Data = append(gNode.Data,
+				graphMLData{Key: "description", Value: node.Description},
+				graphMLData{Key: "h1", Value: node.H1},
+				graphMLData{Key: "lang", Value: node.Lang},
+				graphMLData{Key: "words", Value: strconv.Itoa(node.WordCount)})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gNode)
+	}
+	for i, edge := range edges {
+		gEdge := graphMLEdge{ID: fmt.Sprintf("e%d", i), Source: ids[edge.Source], Target: ids[edge.Target], Data: []graphMLData{
+			{Key: "kind", Value: edge.Kind},
+		}}
+		if edge.Kind == EdgeAsset {
+			gEdge.Data = append(gEdge.Data, graphMLData{Key: "estyle", Value: "dashed"}, graphMLData{Key: "color", Value: "blue"})
+		} else {
+			gEdge.Data = append(gEdge.Data, graphMLData{Key: "label", Value: strconv.Itoa(int(edge.Card))})
+			if edge.Anchor != "" {
+				gEdge.Data = append(gEdge.Data, graphMLData{Key: "anchor", Value: edge.Anchor})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, gEdge)
+	}
+
+	return writeXML(gml.out, &doc)
+}
+
+func writeXML(out io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
@@ -0,0 +1,333 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SecurityHeaders are the response headers of pages kept
+// for the header audit, in canonical form.
+var SecurityHeaders = []string{
+	"Content-Security-Policy",
+	"Strict-Transport-Security",
+	"X-Content-Type-Options",
+	"X-Frame-Options",
+	"Referrer-Policy",
+}
+
+// Cookie is how a cookie set by a page is flagged, see wire.Cookie.
+type Cookie struct {
+	Name     string `json:"name"`
+	Secure   bool   `json:"secure,omitempty"`
+	HTTPOnly bool   `json:"http_only,omitempty"`
+	SameSite string `json:"same_site,omitempty"`
+}
+
+func cookies(wireCookies []wire.Cookie) []Cookie {
+	var converted []Cookie
+	for _, cookie := range wireCookies {
+		converted = append(converted, Cookie(cookie))
+	}
+	return converted
+}
+
+// The flags of cookie, as in Set-Cookie.
+func (cookie Cookie) flags() string {
+	var flags []string
+	if cookie.Secure {
+		flags = append(flags, "Secure")
+	}
+	if cookie.HTTPOnly {
+		flags = append(flags, "HttpOnly")
+	}
+	if cookie.SameSite != "" {
+		flags = append(flags, "SameSite="+cookie.SameSite)
+	}
+	if len(flags) == 0 {
+		return "(no flags)"
+	}
+	return strings.Join(flags, " ")
+}
+
+// HeaderChecks are the checks of the header audit, the columns of its matrix.
+var HeaderChecks = []string{"csp", "hsts", "nosniff", "framing", "referrer", "cookies"}
+
+// Results of header checks, besides what is wrong.
+const (
+	HeaderOK      = "ok"
+	HeaderMissing = "missing"
+	// The check does not apply, HSTS of http pages
+	// or cookie flags of pages without cookies.
+	HeaderNA = "n/a"
+)
+
+// Directives of a header like Content-Security-Policy, "name value",
+// or Strict-Transport-Security, "name=value", lower cased name to value.
+func headerDirectives(value string) map[string]string {
+	directives := make(map[string]string)
+	for _, directive := range strings.Split(value, ";") {
+		directive = strings.TrimSpace(directive)
+		name, value := directive, ""
+		if end := strings.IndexAny(directive, " ="); end >= 0 {
+			name, value = directive[:end], strings.TrimSpace(directive[end+1:])
+		}
+		if name != "" {
+			directives[strings.ToLower(name)] = value
+		}
+	}
+	return directives
+}
+
+// CheckHeaders returns the result of every one of HeaderChecks for the
+// crawled page node: HeaderOK, HeaderMissing, HeaderNA or what is wrong.
+func CheckHeaders(node Node) map[string]string {
+	header := func(name string) (string, bool) {
+		value, exists := node.Headers[name]
+		return value, exists
+	}
+	checks := make(map[string]string, len(HeaderChecks))
+	https := strings.HasPrefix(node.URL, "https://")
+
+	csp, hasCSP := header("Content-Security-Policy")
+	checks["csp"] = HeaderMissing
+	if hasCSP {
+		checks["csp"] = HeaderOK
+	}
+
+	checks["hsts"] = HeaderNA
+	if hsts, exists := header("Strict-Transport-Security"); https && !exists {
+		checks["hsts"] = HeaderMissing
+	} else if https {
+		maxAge, err := strconv.Atoi(strings.Trim(headerDirectives(hsts)["max-age"], `"`))
+		switch {
+		case err != nil:
+			checks["hsts"] = "no max-age"
+		case maxAge <= 0:
+			checks["hsts"] = "max-age=0"
+		default:
+			checks["hsts"] = HeaderOK
+		}
+	}
+
+	checks["nosniff"] = HeaderMissing
+	if value, exists := header("X-Content-Type-Options"); exists && strings.EqualFold(strings.TrimSpace(value), "nosniff") {
+		checks["nosniff"] = HeaderOK
+	} else if exists {
+		checks["nosniff"] = "invalid " + value
+	}
+
+	checks["framing"] = HeaderMissing
+	frameOptions, hasFrameOptions := header("X-Frame-Options")
+	frameOptions = strings.ToLower(strings.TrimSpace(frameOptions))
+	if _, exists := headerDirectives(csp)["frame-ancestors"]; exists || frameOptions == "deny" || frameOptions == "sameorigin" {
+		checks["framing"] = HeaderOK
+	} else if hasFrameOptions {
+		checks["framing"] = "invalid " + frameOptions
+	}
+
+	checks["referrer"] = HeaderMissing
+	if value, exists := header("Referrer-Policy"); exists {
+		// The last policy a browser knows wins.
+		policies := strings.Split(value, ",")
+		policy := strings.ToLower(strings.TrimSpace(policies[len(policies)-1]))
+		checks["referrer"] = HeaderOK
+		if policy == "unsafe-url" {
+			checks["referrer"] = policy
+		}
+	}
+
+	checks["cookies"] = HeaderNA
+	var problems []string
+	for _, cookie := range node.Cookies {
+		var missing []string
+		if https && !cookie.Secure {
+			missing = append(missing, "no Secure")
+		}
+		if !cookie.HTTPOnly {
+			missing = append(missing, "no HttpOnly")
+		}
+		if cookie.SameSite == "" {
+			missing = append(missing, "no SameSite")
+		} else if cookie.SameSite == "none" && !cookie.Secure {
+			missing = append(missing, "SameSite=None without Secure")
+		}
+		if len(missing) > 0 {
+			problems = append(problems, cookie.Name+": "+strings.Join(missing, ", "))
+		}
+	}
+	if len(node.Cookies) > 0 {
+		checks["cookies"] = HeaderOK
+		if len(problems) > 0 {
+			checks["cookies"] = strings.Join(problems, "; ")
+		}
+	}
+	return checks
+}
+
+type headerPrinter struct {
+	graph   *graph
+	matrix  io.Writer
+	summary io.Writer
+}
+
+// NewHeaderAudit returns a GraphProcessor which audits the security
+// headers and cookie flags of every crawled page. It writes the matrix
+// of pages and HeaderChecks, see CheckHeaders, as csv to matrix and
+// the headers and cookies configured differently across pages, and the
+// cookies only some pages set, to summary.
+func NewHeaderAudit(matrix, summary io.Writer) wire.GraphProcessor {
+	return &headerPrinter{graph: newGraph(), matrix: matrix, summary: summary}
+}
+
+func (audit *headerPrinter) ProcessPage(iPage *wire.Page) error {
+	audit.graph.addPage(iPage)
+	return nil
+}
+
+func (audit *headerPrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, _ := audit.graph.sorted()
+	var pages []Node
+	for _, node := range nodes {
+		if node.Type == NodePage && node.Status != 0 {
+			pages = append(pages, node)
+		}
+	}
+	if err := audit.writeMatrix(pages); err != nil {
+		return err
+	}
+	return audit.writeSummary(pages)
+}
+
+func (audit *headerPrinter) writeMatrix(pages []Node) error {
+	writer := csv.NewWriter(audit.matrix)
+	writer.Write(append([]string{"url", "status"}, HeaderChecks...))
+	for _, page := range pages {
+		checks := CheckHeaders(page)
+		row := []string{page.URL, strconv.Itoa(page.Status)}
+		for _, check := range HeaderChecks {
+			row = append(row, checks[check])
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Value of a header missing from a page in the summary.
+const missingValue = "(missing)"
+
+func (audit *headerPrinter) writeSummary(pages []Node) error {
+	// The headers, and the cookies set by any page by name.
+	var names []string
+	names = append(names, SecurityHeaders...)
+	cookieNames := make(map[string]bool)
+	for _, page := range pages {
+		for _, cookie := range page.Cookies {
+			name := "Set-Cookie " + cookie.Name
+			if !cookieNames[name] {
+				cookieNames[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names[len(SecurityHeaders):])
+
+	// Values of every header, and the flags of every cookie, to the
+	// pages with them. Headers are missingValue on the rest, cookies
+	// are only counted on the pages setting them.
+	values := make(map[string]map[string]int)
+	for _, name := range names {
+		values[name] = make(map[string]int)
+	}
+	for _, page := range pages {
+		pageValues := make(map[string]string)
+		for _, name := range SecurityHeaders {
+			if value, exists := page.Headers[name]; exists {
+				pageValues[name] = value
+			}
+		}
+		for _, cookie := range page.Cookies {
+			if name := "Set-Cookie " + cookie.Name; pageValues[name] == "" {
+				pageValues[name] = cookie.flags()
+			}
+		}
+		for _, name := range names {
+			value, exists := pageValues[name]
+			if !exists {
+				if cookieNames[name] {
+					continue
+				}
+				value = missingValue
+			}
+			values[name][value]++
+		}
+	}
+
+	var inconsistent, missing, consistent []string
+	for _, name := range names {
+		switch {
+		case len(values[name]) > 1:
+			inconsistent = append(inconsistent, name)
+		case values[name][missingValue] > 0:
+			missing = append(missing, name)
+		case len(values[name]) == 1:
+			consistent = append(consistent, name)
+		}
+	}
+	// Number of pages setting every cookie not set by all.
+	var partial []string
+	setOn := make(map[string]int)
+	for _, name := range names[len(SecurityHeaders):] {
+		for _, count := range values[name] {
+			setOn[name] += count
+		}
+		if setOn[name] < len(pages) {
+			partial = append(partial, name)
+		}
+	}
+
+	buf := bufio.NewWriter(audit.summary)
+	fmt.Fprintf(buf, "Security headers of %d pages\n", len(pages))
+	fmt.Fprintf(buf, "\nInconsistent across pages (%d)\n", len(inconsistent))
+	for _, name := range inconsistent {
+		fmt.Fprintln(buf, name)
+		var pageValues []string
+		for value := range values[name] {
+			pageValues = append(pageValues, value)
+		}
+		sort.Slice(pageValues, func(i, j int) bool {
+			if values[name][pageValues[i]] != values[name][pageValues[j]] {
+				return values[name][pageValues[i]] > values[name][pageValues[j]]
+			}
+			return pageValues[i] < pageValues[j]
+		})
+		for _, value := range pageValues {
+			fmt.Fprintf(buf, "%-8d%s\n", values[name][value], value)
+		}
+	}
+	fmt.Fprintf(buf, "\nMissing on every page (%d)\n", len(missing))
+	for _, name := range missing {
+		fmt.Fprintln(buf, name)
+	}
+	fmt.Fprintf(buf, "\nSame on every page (%d)\n", len(consistent))
+	for _, name := range consistent {
+		for value := range values[name] {
+			fmt.Fprintf(buf, "%s: %s\n", name, value)
+		}
+	}
+	fmt.Fprintf(buf, "\nCookies not set on every page (%d)\n", len(partial))
+	for _, name := range partial {
+		fmt.Fprintf(buf, "%s: set on %d of %d pages\n", name, setOn[name], len(pages))
+	}
+	return buf.Flush()
+}
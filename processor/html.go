@@ -0,0 +1,274 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"html/template"
+	"io"
+)
+
+type htmlPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewHTML returns a GraphProcessor which writes a single offline
+// HTML report to out, with the JSONDocument of the crawl and a
+// force directed viewer of its graph embedded in it.
+func NewHTML(out io.Writer) wire.GraphProcessor {
+	return &htmlPrinter{graph: newGraph(), out: out}
+}
+
+func (htmlP *htmlPrinter) ProcessPage(iPage *wire.Page) error {
+	htmlP.graph.addPage(iPage)
+	return nil
+}
+
+func (htmlP *htmlPrinter) Finish(info *wire.CrawlInfo) error {
+	doc := JSONDocument{
+		Version:   JSONVersion,
+		RootURL:   info.RootURL,
+		StartTime: info.StartTime,
+		EndTime:   info.EndTime,
+		Statistics: JSONStats{
+			Success:   info.Stats.Success,
+			Skipped:   info.Stats.Skipped,
+			Failed:    info.Stats.Failed,
+			Cancelled: info.Stats.Cancelled,
+		},
+	}
+	doc.Nodes, doc.Edges = htmlP.graph.sorted()
+	setClickDepths(doc.Nodes, doc.Edges, info.RootURL)
+	// html/template escapes the document as JSON inside the script.
+	return htmlReport.Execute(htmlP.out, &doc)
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>dotler: {{.RootURL}}</title>
+<style>
+body { margin: 0; font: 13px sans-serif; display: flex; height: 100vh; }
+#side { width: 280px; padding: 10px; overflow: auto; border-right: 1px solid #ccc; box-sizing: border-box; }
+#side input[type=search] { width: 100%; box-sizing: border-box; }
+#side table { border-collapse: collapse; margin: 8px 0; }
+#side td { padding: 1px 8px 1px 0; }
+#side ul { padding-left: 16px; word-break: break-all; }
+#view { flex: 1; }
+canvas { display: block; }
+</style>
+</head>
+<body>
+<div id="side">
+<h3>{{.RootURL}}</h3>
+<table>
+<tr><td>Started</td><td>{{.StartTime.Format "2006-01-02 15:04:05"}}</td></tr>
+<tr><td>Took</td><td>{{.EndTime.Sub .StartTime}}</td></tr>
+<tr><td>Success</td><td>{{.Statistics.Success}}</td></tr>
+<tr><td>Skipped</td><td>{{.Statistics.Skipped}}</td></tr>
+<tr><td>Failed</td><td>{{.Statistics.Failed}}</td></tr>
+<tr><td>Cancelled</td><td>{{.Statistics.Cancelled}}</td></tr>
+<tr><td>Nodes</td><td>{{len .Nodes}}</td></tr>
+<tr><td>Edges</td><td>{{len .Edges}}</td></tr>
+</table>
+<input id="search" type="search" placeholder="Search url">
+<label><input id="assets" type="checkbox" checked> Static assets</label>
+<div id="info"></div>
+</div>
+<div id="view"><canvas id="canvas"></canvas></div>
+<script>
+const doc = {{.}};
+(function() {
+  const canvas = document.getElementById("canvas");
+  const ctx = canvas.getContext("2d");
+  const info = document.getElementById("info");
+  const byURL = {};
+  const nodes = doc.nodes.map(function(n, i) {
+    const a = 2 * Math.PI * i / doc.nodes.length;
+    const node = {n: n, x: Math.cos(a) * 200, y: Math.sin(a) * 200, vx: 0, vy: 0, ins: [], outs: []};
+    byURL[n.url] = node;
+    return node;
+  });
+  const edges = doc.edges.map(function(e) {
+    const edge = {e: e, s: byURL[e.source], t: byURL[e.target]};
+    edge.s.outs.push(edge);
+    edge.t.ins.push(edge);
+    return edge;
+  });
+  let showAssets = true, query = "", selected = null, steps = 300;
+  let scale = 1, panX = 0, panY = 0, drag = null;
+
+  function visible(node) {
+    return showAssets || node.n.type !== "asset";
+  }
+
+  // Plain force directed layout, repulsion between all nodes
+  // and springs along the edges, cooling down after steps.
+  function step() {
+    const vis = nodes.filter(visible);
+    for (let i = 0; i < vis.length; i++) {
+      for (let j = i + 1; j < vis.length; j++) {
+        const a = vis[i], b = vis[j];
+        let dx = a.x - b.x, dy = a.y - b.y;
+        const d2 = dx * dx + dy * dy + 0.01;
+        const f = 800 / d2;
+        a.vx += dx * f; a.vy += dy * f;
+        b.vx -= dx * f; b.vy -= dy * f;
+      }
+    }
+    edges.forEach(function(edge) {
+      if (!visible(edge.t) || edge.s === edge.t) return;
+      const dx = edge.t.x - edge.s.x, dy = edge.t.y - edge.s.y;
+      const d = Math.sqrt(dx * dx + dy * dy) + 0.01;
+      const f = (d - 60) * 0.02 / d;
+      edge.s.vx += dx * f; edge.s.vy += dy * f;
+      edge.t.vx -= dx * f; edge.t.vy -= dy * f;
+    });
+    vis.forEach(function(node) {
+      node.vx -= node.x * 0.002; node.vy -= node.y * 0.002;
+      node.x += Math.max(-20, Math.min(20, node.vx));
+      node.y += Math.max(-20, Math.min(20, node.vy));
+      node.vx *= 0.6; node.vy *= 0.6;
+    });
+  }
+
+  function related(node) {
+    if (!selected) return true;
+    return node === selected ||
+      selected.outs.some(function(e) { return e.t === node; }) ||
+      selected.ins.some(function(e) { return e.s === node; });
+  }
+
+  function draw() {
+    const w = canvas.width, h = canvas.height;
+    ctx.setTransform(1, 0, 0, 1, 0, 0);
+    ctx.clearRect(0, 0, w, h);
+    ctx.setTransform(scale, 0, 0, scale, w / 2 + panX, h / 2 + panY);
+    edges.forEach(function(edge) {
+      if (!visible(edge.t)) return;
+      const hot = selected && (edge.s === selected || edge.t === selected);
+      ctx.globalAlpha = selected && !hot ? 0.1 : 0.6;
+      ctx.strokeStyle = hot ? (edge.s === selected ? "#d62728" : "#2ca02c") : (edge.e.kind === "asset" ? "#1f77b4" : "#999");
+      ctx.setLineDash(edge.e.kind === "asset" ? [3, 3] : []);
+      ctx.beginPath();
+      ctx.moveTo(edge.s.x, edge.s.y);
+      ctx.lineTo(edge.t.x, edge.t.y);
+      ctx.stroke();
+    });
+    ctx.setLineDash([]);
+    nodes.forEach(function(node) {
+      if (!visible(node)) return;
+      const match = query && node.n.url.toLowerCase().indexOf(query) >= 0;
+      ctx.globalAlpha = related(node) ? 1 : 0.15;
+      ctx.fillStyle = node.n.type === "asset" ? "#aec7e8" : (node.n.status >= 400 || !node.n.status ? "#ff9896" : "#1f77b4");
+      ctx.beginPath();
+      ctx.arc(node.x, node.y, match || node === selected ? 8 : 5, 0, 2 * Math.PI);
+      ctx.fill();
+      if (match) {
+        ctx.strokeStyle = "#ff7f0e";
+        ctx.lineWidth = 3;
+        ctx.stroke();
+        ctx.lineWidth = 1;
+      }
+    });
+    ctx.globalAlpha = 1;
+  }
+
+  function frame() {
+    if (steps > 0) {
+      step();
+      steps--;
+    }
+    draw();
+    requestAnimationFrame(frame);
+  }
+
+  function resize() {
+    canvas.width = canvas.parentNode.clientWidth;
+    canvas.height = canvas.parentNode.clientHeight;
+  }
+
+  function at(ev) {
+    const x = (ev.offsetX - canvas.width / 2 - panX) / scale;
+    const y = (ev.offsetY - canvas.height / 2 - panY) / scale;
+    let best = null, bestD = 100 / (scale * scale);
+    nodes.forEach(function(node) {
+      if (!visible(node)) return;
+      const d = (node.x - x) * (node.x - x) + (node.y - y) * (node.y - y);
+      if (d < bestD) { best = node; bestD = d; }
+    });
+    return best;
+  }
+
+  function text(tag, value) {
+    const el = document.createElement(tag);
+    el.textContent = value;
+    return el;
+  }
+
+  function list(title, links) {
+    info.appendChild(text("h4", title + " (" + links.length + ")"));
+    const ul = document.createElement("ul");
+    links.forEach(function(link) { ul.appendChild(text("li", link)); });
+    info.appendChild(ul);
+  }
+
+  function select(node) {
+    selected = node;
+    info.textContent = "";
+    if (!node) return;
+    const a = document.createElement("a");
+    a.href = node.n.url;
+    a.textContent = node.n.url;
+    info.appendChild(a);
+    if (node.n.title) info.appendChild(text("p", node.n.title));
+    if (node.n.h1) info.appendChild(text("p", "h1: " + node.n.h1));
+    if (node.n.description) info.appendChild(text("p", node.n.description));
+    if (node.n.status) info.appendChild(text("p", (node.n.lang ? "lang " + node.n.lang + ", " : "") + (node.n.word_count || 0) + " words"));
+    info.appendChild(text("p", node.n.type + ", status " + (node.n.status || "not crawled") + ", depth " + node.n.depth));
+    function anchor(e) { return e.e.anchor ? " \u201c" + e.e.anchor + "\u201d" : ""; }
+    list("Outbound", node.outs.map(function(e) { return e.e.target + " (" + e.e.card + ")" + anchor(e); }));
+    list("Inbound", node.ins.map(function(e) { return e.e.source + " (" + e.e.card + ")" + anchor(e); }));
+  }
+
+  canvas.addEventListener("mousedown", function(ev) {
+    drag = {x: ev.offsetX, y: ev.offsetY, moved: false};
+  });
+  canvas.addEventListener("mousemove", function(ev) {
+    if (!drag) return;
+    panX += ev.offsetX - drag.x;
+    panY += ev.offsetY - drag.y;
+    drag.moved = drag.moved || Math.abs(ev.offsetX - drag.x) + Math.abs(ev.offsetY - drag.y) > 2;
+    drag.x = ev.offsetX;
+    drag.y = ev.offsetY;
+  });
+  canvas.addEventListener("mouseup", function(ev) {
+    if (drag && !drag.moved) select(at(ev));
+    drag = null;
+  });
+  canvas.addEventListener("wheel", function(ev) {
+    ev.preventDefault();
+    scale *= ev.deltaY < 0 ? 1.1 : 1 / 1.1;
+  });
+  document.getElementById("search").addEventListener("input", function(ev) {
+    query = ev.target.value.toLowerCase();
+    const matches = nodes.filter(function(node) { return query && visible(node) && node.n.url.toLowerCase().indexOf(query) >= 0; });
+    if (matches.length === 1) select(matches[0]);
+  });
+  document.getElementById("assets").addEventListener("change", function(ev) {
+    showAssets = ev.target.checked;
+    if (selected && !visible(selected)) select(null);
+    steps = 150;
+  });
+  window.addEventListener("resize", resize);
+  resize();
+  frame();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,100 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// JSONVersion is the version of the JSON document schema.
+const JSONVersion = 1
+
+// JSONDocument is the graph written by the JSON processor:
+//
+//	{
+//	  "version": 1,
+//	  "root_url": "http://www.wnohang.net/",
+//	  "start_time": "2017-01-23T09:40:58Z",
+//	  "end_time": "2017-01-23T09:41:13Z",
+//	  "statistics": {"success": 7, "skipped": 0, "failed": 0, "cancelled": 0},
+//	  "nodes": [
+//	    {"url": "http://www.wnohang.net/", "title": "wnohang", "description": "Home of wnohang", "h1": "wnohang",
+//	     "lang": "en", "word_count": 212, "status": 200, "depth": 0, "type": "page"},
+//	    {"url": "http://www.wnohang.net/main.css", "title": "main.css", "type": "asset"}
+//	  ],
+//	  "edges": [
+//	    {"source": "http://www.wnohang.net/", "target": "http://www.wnohang.net/about", "card": 1, "kind": "link", "anchor": "About",
+//	     "links": [{"text": "About", "title": "About me", "rel": ["author"], "region": "nav"}]},
+//	    {"source": "http://www.wnohang.net/", "target": "http://www.wnohang.net/main.css", "card": 1, "kind": "asset"}
+//	  ],
+//	  "external_checks": [
+//	    {"url": "https://golang.org/doc/", "status": 200},
+//	    {"url": "https://example.com/gone", "status": 404}
+//	  ]
+//	}
+//
+// Nodes are sorted by url, edges by source and then target,
+// so documents of two runs can be diffed.
+// A page node with status 0 was linked to but never crawled.
+// ExternalChecks are only there if external links were checked.
+type JSONDocument struct {
+	Version    int       `json:"version"`
+	RootURL    string    `json:"root_url"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	Statistics JSONStats `json:"statistics"`
+	Nodes      []Node    `json:"nodes"`
+	Edges      []Edge    `json:"edges"`
+	// ExternalChecks are sorted by url.
+	ExternalChecks []ExternalCheck `json:"external_checks,omitempty"`
+}
+
+// JSONStats are the crawl statistics in a JSONDocument.
+type JSONStats struct {
+	Success   uint64 `json:"success"`
+	Skipped   uint64 `json:"skipped"`
+	Failed    uint64 `json:"failed"`
+	Cancelled uint64 `json:"cancelled"`
+}
+
+type jsonPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewJSON returns a GraphProcessor which writes a JSONDocument to out.
+func NewJSON(out io.Writer) wire.GraphProcessor {
+	return &jsonPrinter{graph: newGraph(), out: out}
+}
+
+func (jsonP *jsonPrinter) ProcessPage(iPage *wire.Page) error {
+	jsonP.graph.addPage(iPage)
+	return nil
+}
+
+func (jsonP *jsonPrinter) Finish(info *wire.CrawlInfo) error {
+	doc := JSONDocument{
+		Version:   JSONVersion,
+		RootURL:   info.RootURL,
+		StartTime: info.StartTime,
+		EndTime:   info.EndTime,
+		Statistics: JSONStats{
+			Success:   info.Stats.Success,
+			Skipped:   info.Stats.Skipped,
+			Failed:    info.Stats.Failed,
+			Cancelled: info.Stats.Cancelled,
+		},
+	}
+	doc.Nodes, doc.Edges = jsonP.graph.sorted()
+	setClickDepths(doc.Nodes, doc.Edges, info.RootURL)
+	doc.ExternalChecks = ExternalChecks(info.External)
+
+	encoder := json.NewEncoder(jsonP.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&doc)
+}
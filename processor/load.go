@@ -0,0 +1,204 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	"github.com/awalterschulze/gographviz"
+	wire "github.com/ronin13/dotler/wire"
+
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// LoadGraph reads a crawl saved by the JSON processor or the graphviz
+// graph of the dot printer from in.
+// A graphviz graph only has the urls, link cardinality and titles
+// of static assets, and no root url, external links or their checks.
+func LoadGraph(in io.Reader) (*JSONDocument, error) {
+	content, err := ioutil.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	if trimmed := bytes.TrimSpace(content); bytes.HasPrefix(trimmed, []byte("{")) {
+		var doc JSONDocument
+		if err = json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		if doc.Version != JSONVersion {
+			return nil, fmt.Errorf("Unsupported version %d of JSON document", doc.Version)
+		}
+		return &doc, nil
+	}
+	return loadDot(content)
+}
+
+// Node names are quoted urls, static assets and links
+// to them are dashed, hosts of external links are boxes,
+// as written by dotPrinter.
+func loadDot(content []byte) (*JSONDocument, error) {
+	dotGraph, err := gographviz.Read(content)
+	if err != nil {
+		return nil, err
+	}
+	unquote := func(name string) string {
+		if unquoted, err := strconv.Unquote(name); err == nil {
+			return unquoted
+		}
+		return name
+	}
+
+	doc := &JSONDocument{Version: JSONVersion}
+	hosts := make(map[string]bool)
+	for _, node := range dotGraph.Nodes.Nodes {
+		if node.Attrs["shape"] == "box" {
+			hosts[node.Name] = true
+			continue
+		}
+		gNode := Node{URL: unquote(node.Name), Type: NodePage}
+		if node.Attrs["style"] == "dashed" {
+			gNode.Type = NodeAsset
+			gNode.Title = unquote(node.Attrs["URL"])
+		}
+		doc.Nodes = append(doc.Nodes, gNode)
+	}
+	for _, edge := range dotGraph.Edges.Edges {
+		if hosts[edge.Dst] {
+			continue
+		}
+		gEdge := Edge{Source: unquote(edge.Src), Target: unquote(edge.Dst), Card: 1, Kind: EdgeLink}
+		if edge.Attrs["style"] == "dashed" {
+			gEdge.Kind = EdgeAsset
+		} else if card, err := strconv.Atoi(unquote(edge.Attrs["label"])); err == nil {
+			gEdge.Card = uint(card)
+		}
+		doc.Edges = append(doc.Edges, gEdge)
+	}
+	sort.Slice(doc.Nodes, func(i, j int) bool {
+		return doc.Nodes[i].URL < doc.Nodes[j].URL
+	})
+	sort.Slice(doc.Edges, func(i, j int) bool {
+		if doc.Edges[i].Source != doc.Edges[j].Source {
+			return doc.Edges[i].Source < doc.Edges[j].Source
+		}
+		return doc.Edges[i].Target < doc.Edges[j].Target
+	})
+	return doc, nil
+}
+
+// Pages turns the document back into the crawled pages, in order of
+// depth and url, with their links and static assets, ready to be fed
+// to GraphProcessors. Pages linked to but never crawled are only
+// in OutLinks.
+func (doc *JSONDocument) Pages() ([]*wire.Page, error) {
+	pages := make(map[string]*wire.Page)
+	nodes := make(map[string]Node, len(doc.Nodes))
+	for _, node := range doc.Nodes {
+		nodes[node.URL] = node
+		if node.Type != NodePage {
+			continue
+		}
+		pageURL, err := url.Parse(node.URL)
+		if err != nil {
+			return nil, err
+		}
+		var hash uint64
+		if node.SimHash != "" {
+			if hash, err = strconv.ParseUint(node.SimHash, 16, 64); err != nil {
+				return nil, fmt.Errorf("Bad simhash of %s: %s", node.URL, err)
+			}
+		}
+		var insecure []wire.InsecureRef
+		for _, ref := range node.Insecure {
+			insecure = append(insecure, wire.InsecureRef(ref))
+		}
+		var wireCookies []wire.Cookie
+		for _, cookie := range node.Cookies {
+			wireCookies = append(wireCookies, wire.Cookie(cookie))
+		}
+		external := make(map[string]uint, len(node.External))
+		for _, link := range node.External {
+			external[link.URL] = link.Card
+		}
+		pages[node.URL] = &wire.Page{
+			PageURL:     pageURL,
+			External:    external,
+			Title:       node.Title,
+			Description: node.Description,
+			H1:          node.H1,
+			H1Count:     node.H1Count,
+			MissingAlt:  node.MissingAlt,
+			Canonical:   node.Canonical,
+			NoIndex:     node.NoIndex,
+			Anchors:     node.Anchors,
+			Insecure:    insecure,
+			Headers:     node.Headers,
+			Cookies:     wireCookies,
+			Lang:        node.Lang,
+			WordCount:   node.WordCount,
+			ContentHash: node.ContentHash,
+			SimHash:     hash,
+			Status:      node.Status,
+			Depth:       node.Depth,
+		}
+	}
+
+	crawled := make(map[string]bool)
+	for _, node := range doc.Nodes {
+		// Graphviz graphs have no status, pages with links were crawled.
+		crawled[node.URL] = node.Type == NodePage && node.Status != 0
+	}
+	for _, edge := range doc.Edges {
+		iPage, exists := pages[edge.Source]
+		if !exists {
+			return nil, fmt.Errorf("Link from unknown page %s", edge.Source)
+		}
+		crawled[edge.Source] = true
+		if iPage.OutLinks == nil {
+			iPage.OutLinks = make(map[string]*wire.PageWithCard)
+			iPage.StatList = make(map[string]wire.StatPage)
+		}
+
+		if edge.Kind == EdgeAsset {
+			statURL, err := url.Parse(edge.Target)
+			if err != nil {
+				return nil, err
+			}
+			iPage.StatList[edge.Target] = wire.StatPage{PageTitle: nodes[edge.Target].Title, StaticURL: statURL}
+			continue
+		}
+		oPage, exists := pages[edge.Target]
+		if !exists {
+			return nil, fmt.Errorf("Link to unknown page %s", edge.Target)
+		}
+		link := &wire.PageWithCard{Page: oPage, Card: edge.Card}
+		for _, eLink := range edge.Links {
+			link.Links = append(link.Links, wire.Link(eLink))
+		}
+		iPage.OutLinks[edge.Target] = link
+	}
+
+	var result []*wire.Page
+	for link, iPage := range pages {
+		if crawled[link] {
+			if iPage.OutLinks == nil {
+				iPage.OutLinks = make(map[string]*wire.PageWithCard)
+				iPage.StatList = make(map[string]wire.StatPage)
+			}
+			result = append(result, iPage)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Depth != result[j].Depth {
+			return result[i].Depth < result[j].Depth
+		}
+		return result[i].PageURL.String() < result[j].PageURL.String()
+	})
+	return result, nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"bufio"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// Severities of insecure references of https pages.
+// Active mixed content, scripts, stylesheets and frames, can take
+// over the page and is blocked by browsers, passive mixed content,
+// images and media, can only be seen or swapped. Downgrades are
+// links to http pages of the same site.
+const (
+	SeverityActive    = "active"
+	SeverityPassive   = "passive"
+	SeverityDowngrade = "downgrade"
+)
+
+// Severities are all the severities, most severe first.
+var Severities = []string{SeverityActive, SeverityPassive, SeverityDowngrade}
+
+var severityTitles = map[string]string{
+	SeverityActive:    "Active mixed content",
+	SeverityPassive:   "Passive mixed content",
+	SeverityDowngrade: "Links downgrading to http",
+}
+
+// InsecureRef is a http:// reference of a https page, see wire.InsecureRef.
+type InsecureRef struct {
+	URL      string `json:"url"`
+	Element  string `json:"element"`
+	Severity string `json:"severity"`
+}
+
+func insecureRefs(refs []wire.InsecureRef) []InsecureRef {
+	var converted []InsecureRef
+	for _, ref := range refs {
+		converted = append(converted, InsecureRef(ref))
+	}
+	return converted
+}
+
+// The most severe severity of the insecure references of node, if any.
+func worstSeverity(node Node) string {
+	for _, severity := range Severities {
+		for _, ref := range node.Insecure {
+			if ref.Severity == severity {
+				return severity
+			}
+		}
+	}
+	return ""
+}
+
+type mixedPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewMixedContentReport returns a GraphProcessor which writes the insecure
+// references of https pages to out, by severity, one per line with the
+// element it is in and the page it is on.
+func NewMixedContentReport(out io.Writer) wire.GraphProcessor {
+	return &mixedPrinter{graph: newGraph(), out: out}
+}
+
+func (mixed *mixedPrinter) ProcessPage(iPage *wire.Page) error {
+	mixed.graph.addPage(iPage)
+	return nil
+}
+
+func (mixed *mixedPrinter) Finish(info *wire.CrawlInfo) error {
+	nodes, _ := mixed.graph.sorted()
+	bySeverity := make(map[string][]string)
+	pages := 0
+	for _, node := range nodes {
+		if len(node.Insecure) > 0 {
+			pages++
+		}
+		for _, ref := range node.Insecure {
+			bySeverity[ref.Severity] = append(bySeverity[ref.Severity],
+				fmt.Sprintf("%-8s%s on %s", ref.Element, ref.URL, node.URL))
+		}
+	}
+
+	buf := bufio.NewWriter(mixed.out)
+	fmt.Fprintf(buf, "Pages with insecure references (%d)\n", pages)
+	for _, severity := range Severities {
+		fmt.Fprintf(buf, "\n%s (%d)\n", severityTitles[severity], len(bySeverity[severity]))
+		for _, line := range bySeverity[severity] {
+			fmt.Fprintln(buf, line)
+		}
+	}
+	return buf.Flush()
+}
+
+// Font colors of pages in the graph by their worst severity.
+var severityColors = map[string]string{
+	SeverityActive:    "red",
+	SeverityPassive:   "orange",
+	SeverityDowngrade: "orange",
+}
+
+// Colors the labels of pages with insecure references
+// by the most severe of them, with it in the tooltip.
+func (dot *dotPrinter) addInsecure() error {
+	nodes, _ := dot.graph.sorted()
+	for _, node := range nodes {
+		severity := worstSeverity(node)
+		if severity == "" {
+			continue
+		}
+		pageURL, err := url.Parse(node.URL)
+		if err != nil {
+			return err
+		}
+		attrs := map[string]string{"fontcolor": severityColors[severity]}
+		if dot.options.Tooltip == "" && !dot.options.Rank {
+			attrs["tooltip"] = fmt.Sprintf("%q", severityTitles[severity])
+		}
+		dot.addNode(fmt.Sprintf("%q", node.URL), pageURL, false, attrs)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	"sort"
+	"strings"
+)
+
+// ShortestPaths returns the first max (0 for all) in sort order of the
+// shortest paths of links from one page to another, each a list of urls
+// from from to to. Returns nil if to can not be reached.
+func ShortestPaths(edges []Edge, from, to string, max int) [][]string {
+	out := make(map[string][]string)
+	for _, edge := range edges {
+		if edge.Kind == EdgeLink && edge.Source != edge.Target {
+			out[edge.Source] = append(out[edge.Source], edge.Target)
+		}
+	}
+
+	// Every page reached is kept with all its predecessors
+	// on shortest paths.
+	dist := map[string]int{from: 0}
+	preds := make(map[string][]string)
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			break
+		}
+		for _, next := range out[current] {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[current] + 1
+				queue = append(queue, next)
+			}
+			if dist[next] == dist[current]+1 {
+				preds[next] = append(preds[next], current)
+			}
+		}
+	}
+	if _, reached := dist[to]; !reached {
+		return nil
+	}
+
+	var paths [][]string
+	var walk func(link string, suffix []string)
+	walk = func(link string, suffix []string) {
+		suffix = append([]string{link}, suffix...)
+		if link == from {
+			paths = append(paths, suffix)
+			return
+		}
+		for _, pred := range preds[link] {
+			walk(pred, suffix)
+		}
+	}
+	walk(to, nil)
+	sort.Slice(paths, func(i, j int) bool {
+		return strings.Join(paths[i], " ") < strings.Join(paths[j], " ")
+	})
+	if max > 0 && len(paths) > max {
+		paths = paths[:max]
+	}
+	return paths
+}
+
+// Subgraph returns a document of only the pages on paths
+// and the links between them along the paths.
+func (doc *JSONDocument) Subgraph(paths [][]string) *JSONDocument {
+	type link struct{ source, target string }
+	onPath := make(map[string]bool)
+	links := make(map[link]bool)
+	for _, path := range paths {
+		for i, page := range path {
+			onPath[page] = true
+			if i > 0 {
+				links[link{path[i-1], page}] = true
+			}
+		}
+	}
+
+	sub := &JSONDocument{
+		Version:    doc.Version,
+		RootURL:    doc.RootURL,
+		StartTime:  doc.StartTime,
+		EndTime:    doc.EndTime,
+		Statistics: doc.Statistics,
+	}
+	for _, node := range doc.Nodes {
+		if onPath[node.URL] {
+			sub.Nodes = append(sub.Nodes, node)
+		}
+	}
+	for _, edge := range doc.Edges {
+		if edge.Kind == EdgeLink && links[link{edge.Source, edge.Target}] {
+			sub.Edges = append(sub.Edges, edge)
+		}
+	}
+	return sub
+}
@@ -6,34 +6,67 @@
 // concurrently when crawling is being done.
 // Persisted only towards end.
 // Gets the input from another channel to
-// which the crawler writes Pages.
-// Renders both Page nodes and Static nodes.
+// which the crawler writes Pages, and fans it
+// out to every GraphProcessor attached.
+// Renders both Page nodes and Static nodes,
+// and the hosts of external links.
 package processor
 
 import (
 	"github.com/awalterschulze/gographviz"
-	"github.com/golang/glog"
 	wire "github.com/ronin13/dotler/wire"
 
-	"context"
 	"fmt"
+	"io"
+	"net/url"
 	"strconv"
+	"strings"
 )
 
-// Adds a Page Node.
+// Adds the Node of a crawled Page.
 func (dot *dotPrinter) addNoteFromAttr(iPage *wire.Page) string {
 	quotedURL := fmt.Sprintf("%q", iPage.PageURL.String())
-	dot.cgraph.AddNode("dotler", quotedURL, map[string]string{
+	attrs := map[string]string{
+		"URL": quotedURL,
+	}
+	if label := pageField(iPage, dot.options.Label); label != "" {
+		attrs["label"] = fmt.Sprintf("%q", label)
+	}
+	if tooltip := pageField(iPage, dot.options.Tooltip); tooltip != "" {
+		attrs["tooltip"] = fmt.Sprintf("%q", tooltip)
+	}
+	dot.addNode(quotedURL, iPage.PageURL, false, attrs)
+	return quotedURL
+}
+
+// Adds the Node of a linked Page, which may still be crawled,
+// by its url alone; label and tooltip are set once it is crawled.
+func (dot *dotPrinter) addLinkedNode(pageURL *url.URL) string {
+	quotedURL := fmt.Sprintf("%q", pageURL.String())
+	dot.addNode(quotedURL, pageURL, false, map[string]string{
 		"URL": quotedURL,
 	})
 	return quotedURL
 }
 
+// Returns the field of iPage named as in DotFields.
+func pageField(iPage *wire.Page, field string) string {
+	switch field {
+	case "title":
+		return iPage.Title
+	case "h1":
+		return iPage.H1
+	case "description":
+		return iPage.Description
+	}
+	return ""
+}
+
 // Adds a Static Node.
 func (dot *dotPrinter) staticNodes(iPage wire.StatPage) string {
 	quotedURL := fmt.Sprintf("%q", iPage.StaticURL.String())
 	quotedTitle := fmt.Sprintf("%q", iPage.PageTitle)
-	dot.cgraph.AddNode("dotler", quotedURL, map[string]string{
+	dot.addNode(quotedURL, iPage.StaticURL, true, map[string]string{
 		"URL":     quotedTitle,
 		"tooltip": quotedURL,
 		"style":   "dashed",
@@ -41,59 +74,184 @@ func (dot *dotPrinter) staticNodes(iPage wire.StatPage) string {
 	return quotedURL
 }
 
+// With clusters, nodes and edges are kept till Finish
+// since the clusters are only known after the crawl.
+func (dot *dotPrinter) addNode(name string, nodeURL *url.URL, static bool, attrs map[string]string) {
+	if dot.options.ClusterDepth <= 0 {
+		dot.cgraph.AddNode("dotler", name, attrs)
+		return
+	}
+	if node, exists := dot.nodes[name]; exists {
+		for key, value := range attrs {
+			node.attrs[key] = value
+		}
+		return
+	}
+	dot.nodes[name] = &dotNode{url: nodeURL, static: static, attrs: attrs}
+}
+
+func (dot *dotPrinter) addEdge(src, dst string, card uint, attrs map[string]string) {
+	if dot.options.ClusterDepth <= 0 {
+		dot.cgraph.AddEdge(src, dst, true, attrs)
+		return
+	}
+	dot.edges = append(dot.edges, dotEdge{src: src, dst: dst, card: card, attrs: attrs})
+}
+
+// DotFields are the page fields DotOptions.Label and Tooltip
+// may be set to, url being the default.
+var DotFields = []string{"url", "title", "h1", "description"}
+
+// DotOptions control the graphviz graph of NewDotPrinter.
+type DotOptions struct {
+	// Label is the field of pages, one of DotFields, shown as their label.
+	// Pages fall back to their url when it is empty.
+	Label string
+	// Tooltip is the field of pages, one of DotFields, shown as their tooltip.
+	Tooltip string
+	// AnchorLabels labels links with their most common anchor
+	// text instead of their cardinality.
+	AnchorLabels bool
+	// ClusterDepth if not 0, groups nodes into nested clusters
+	// by up to ClusterDepth segments of their path, /blog/, /blog/2016/..
+	// Clusters of a single node are left out.
+	ClusterDepth int
+	// Collapse draws the innermost clusters as a single node with
+	// the number of pages in them.
+	Collapse bool
+	// Rank sizes and colors pages by their PageRank, see Rank.
+	Rank bool
+	// Duplicates outlines pages with the same content in the
+	// same color, see DuplicateGroups.
+	Duplicates bool
+	// Insecure colors the labels of https pages with http:// references
+	// by the most severe of them, red for active mixed content.
+	Insecure bool
+}
+
+// Validate checks options for errors.
+func (options DotOptions) Validate() error {
+	if options.ClusterDepth < 0 {
+		return fmt.Errorf("Negative graph cluster depth %d", options.ClusterDepth)
+	}
+	for _, field := range []string{options.Label, options.Tooltip} {
+		valid := field == ""
+		for _, dotField := range DotFields {
+			valid = valid || field == dotField
+		}
+		if !valid {
+			return fmt.Errorf("Unknown page field %s, need one of %s", field, strings.Join(DotFields, ", "))
+		}
+	}
+	return nil
+}
+
+type dotNode struct {
+	url    *url.URL
+	static bool
+	attrs  map[string]string
+}
+
+type dotEdge struct {
+	src, dst string
+	card     uint
+	attrs    map[string]string
+}
+
 type dotPrinter struct {
-	cgraph *gographviz.Escape
-	result chan string
+	cgraph  *gographviz.Escape
+	out     io.Writer
+	options DotOptions
+	nodes   map[string]*dotNode
+	edges   []dotEdge
+	// External links of pages, to color their hosts if broken.
+	external map[string]bool
+	// Kept for Rank, Duplicates and Insecure.
+	graph *graph
+}
+
+// NewPrinter returns a new instance implementing the GraphProcessor interface,
+// which writes the graphviz graph to out.
+func NewPrinter(out io.Writer) wire.GraphProcessor {
+	return NewDotPrinter(out, DotOptions{})
 }
 
-// NewPrinter returns a new instance implementing the GraphProcessor interface.
-func NewPrinter() wire.GraphProcessor {
+// NewDotPrinter is NewPrinter with options.
+func NewDotPrinter(out io.Writer, options DotOptions) wire.GraphProcessor {
 	dPrinter := new(dotPrinter)
 	dPrinter.cgraph = gographviz.NewEscape()
-	dPrinter.result = make(chan string, 1)
+	dPrinter.out = out
+	dPrinter.options = options
+	dPrinter.nodes = make(map[string]*dotNode)
+	dPrinter.external = make(map[string]bool)
+	if options.Rank || options.Duplicates || options.Insecure {
+		dPrinter.graph = newGraph()
+	}
 	dPrinter.cgraph.SetName("dotler")
 	dPrinter.cgraph.SetDir(true)
 	dPrinter.cgraph.SetStrict(true)
 	return dPrinter
 }
 
-func (dot *dotPrinter) Result() chan string {
-	return dot.result
+// Weaves the page, its outlinks and static assets into the graph.
+func (dot *dotPrinter) ProcessPage(iPage *wire.Page) error {
+	var addedURL, presURL string
+
+	if dot.graph != nil {
+		dot.graph.addPage(iPage)
+	}
+	presURL = dot.addNoteFromAttr(iPage)
+	for _, oPage := range iPage.OutLinks {
+		addedURL = dot.addLinkedNode(oPage.Page.PageURL)
+		label := strconv.Itoa(int(oPage.Card))
+		if anchor := oPage.Anchor(); dot.options.AnchorLabels && anchor != "" {
+			label = fmt.Sprintf("%q", anchor)
+		}
+		dot.addEdge(presURL, addedURL, oPage.Card, map[string]string{
+			"label": label,
+		})
+	}
+
+	for _, sPage := range iPage.StatList {
+		addedURL = dot.staticNodes(sPage)
+		dot.addEdge(presURL, addedURL, 0, map[string]string{
+			"style": "dashed",
+			"color": "blue",
+		})
+	}
+
+	if len(iPage.External) > 0 {
+		dot.addExternal(presURL, iPage)
+	}
+	return nil
 }
 
-// Runs till the inward channel closes, during shutdown.
-// Runs in parallel with crawler, silently weaving the graph
-// in background.
-func (dot *dotPrinter) ProcessLoop(noPrint context.Context, inChan chan *wire.Page) {
-	glog.Infoln("Starting the dot printer!")
-	var addedURL, presURL string
-	go func() {
-		for {
-			select {
-			case iPage := <-inChan:
-				if iPage != nil {
-					presURL = dot.addNoteFromAttr(iPage)
-					for _, oPage := range iPage.OutLinks {
-						addedURL = dot.addNoteFromAttr(oPage.Page)
-						dot.cgraph.AddEdge(presURL, addedURL, true, map[string]string{
-							"label": strconv.Itoa(int(oPage.Card)),
-						})
-					}
-
-					for _, sPage := range iPage.StatList {
-						addedURL = dot.staticNodes(sPage)
-						dot.cgraph.AddEdge(presURL, addedURL, true, map[string]string{
-							"style": "dashed",
-							"color": "blue",
-						})
-					}
-				}
-
-			case <-noPrint.Done():
-				dot.result <- dot.cgraph.String()
-				glog.Infoln("Halting the dot printer!")
-				return
-			}
+func (dot *dotPrinter) Finish(info *wire.CrawlInfo) error {
+	if dot.options.Rank {
+		if err := dot.addRanks(); err != nil {
+			return err
+		}
+	}
+	if dot.options.Duplicates {
+		if err := dot.addDuplicates(); err != nil {
+			return err
+		}
+	}
+	if dot.options.Insecure {
+		if err := dot.addInsecure(); err != nil {
+			return err
+		}
+	}
+	if info.External != nil {
+		dot.addBrokenExternal(info.External)
+	}
+	if dot.options.ClusterDepth > 0 {
+		rootURL, err := url.Parse(info.RootURL)
+		if err != nil {
+			return err
 		}
-	}()
+		dot.addClusters(rootURL)
+	}
+	_, err := io.WriteString(dot.out, dot.cgraph.String())
+	return err
 }
@@ -0,0 +1,235 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+const (
+	// Probability of following a link rather than jumping to any page.
+	rankDamping = 0.85
+	// PageRank iterations stop once ranks change less than this, in sum.
+	rankEpsilon = 1e-9
+	rankMaxIter = 100
+)
+
+// PageRank is the standing of a page in the internal link graph.
+// InDegree and OutDegree count distinct linking and linked pages,
+// Betweenness is normalized to 0..1.
+type PageRank struct {
+	URL         string
+	Rank        float64
+	InDegree    int
+	OutDegree   int
+	Betweenness float64
+}
+
+// Rank computes PageRank, weighted by link cardinality, degrees and
+// betweenness centrality of the pages in nodes, over the links in edges.
+// Static assets and links of a page to itself are left out.
+// Returns pages sorted by descending rank, then url.
+func Rank(nodes []Node, edges []Edge) []PageRank {
+	index := make(map[string]int)
+	var ranks []PageRank
+	for _, node := range nodes {
+		if node.Type == NodePage {
+			index[node.URL] = len(ranks)
+			ranks = append(ranks, PageRank{URL: node.URL})
+		}
+	}
+	count := len(ranks)
+	if count == 0 {
+		return ranks
+	}
+
+	// Adjacency with the cardinality of links.
+	out := make([]map[int]uint, count)
+	for i := range out {
+		out[i] = make(map[int]uint)
+	}
+	for _, edge := range edges {
+		source, sExists := index[edge.Source]
+		target, tExists := index[edge.Target]
+		if edge.Kind != EdgeLink || !sExists || !tExists || source == target {
+			continue
+		}
+		if _, exists := out[source][target]; !exists {
+			ranks[source].OutDegree++
+			ranks[target].InDegree++
+		}
+		out[source][target] += edge.Card
+	}
+
+	pageRank(ranks, out)
+	betweenness(ranks, out)
+
+	sort.Slice(ranks, func(i, j int) bool {
+		if ranks[i].Rank != ranks[j].Rank {
+			return ranks[i].Rank > ranks[j].Rank
+		}
+		return ranks[i].URL < ranks[j].URL
+	})
+	return ranks
+}
+
+// Power iteration, rank of pages without links is spread over all.
+func pageRank(ranks []PageRank, out []map[int]uint) {
+	count := float64(len(ranks))
+	weights := make([]uint, len(ranks))
+	for i := range out {
+		for _, card := range out[i] {
+			weights[i] += card
+		}
+	}
+
+	current := make([]float64, len(ranks))
+	for i := range current {
+		current[i] = 1 / count
+	}
+	next := make([]float64, len(ranks))
+	for iter := 0; iter < rankMaxIter; iter++ {
+		dangling := 0.0
+		for i := range current {
+			if weights[i] == 0 {
+				dangling += current[i]
+			}
+		}
+		for i := range next {
+			next[i] = (1-rankDamping)/count + rankDamping*dangling/count
+		}
+		for i := range out {
+			for j, card := range out[i] {
+				next[j] += rankDamping * current[i] * float64(card) / float64(weights[i])
+			}
+		}
+		change := 0.0
+		for i := range next {
+			change += math.Abs(next[i] - current[i])
+		}
+		current, next = next, current
+		if change < rankEpsilon {
+			break
+		}
+	}
+	for i := range ranks {
+		ranks[i].Rank = current[i]
+	}
+}
+
+// Brandes' algorithm over unweighted shortest paths.
+func betweenness(ranks []PageRank, out []map[int]uint) {
+	count := len(ranks)
+	centrality := make([]float64, count)
+	sigma := make([]float64, count)
+	dist := make([]int, count)
+	delta := make([]float64, count)
+	preds := make([][]int, count)
+
+	for source := 0; source < count; source++ {
+		var stack []int
+		for i := 0; i < count; i++ {
+			sigma[i], dist[i], delta[i], preds[i] = 0, -1, 0, preds[i][:0]
+		}
+		sigma[source], dist[source] = 1, 0
+		queue := []int{source}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			stack = append(stack, current)
+			for next := range out[current] {
+				if dist[next] < 0 {
+					dist[next] = dist[current] + 1
+					queue = append(queue, next)
+				}
+				if dist[next] == dist[current]+1 {
+					sigma[next] += sigma[current]
+					preds[next] = append(preds[next], current)
+				}
+			}
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			current := stack[i]
+			for _, pred := range preds[current] {
+				delta[pred] += sigma[pred] / sigma[current] * (1 + delta[current])
+			}
+			if current != source {
+				centrality[current] += delta[current]
+			}
+		}
+	}
+
+	if count > 2 {
+		for i := range ranks {
+			ranks[i].Betweenness = centrality[i] / float64((count-1)*(count-2))
+		}
+	}
+}
+
+var csvRankHeader = []string{"rank", "url", "pagerank", "in_degree", "out_degree", "betweenness"}
+
+type rankPrinter struct {
+	graph *graph
+	out   io.Writer
+}
+
+// NewRankReport returns a GraphProcessor which writes the pages
+// ranked by Rank to out as csv, one row per page.
+func NewRankReport(out io.Writer) wire.GraphProcessor {
+	return &rankPrinter{graph: newGraph(), out: out}
+}
+
+func (rank *rankPrinter) ProcessPage(iPage *wire.Page) error {
+	rank.graph.addPage(iPage)
+	return nil
+}
+
+func (rank *rankPrinter) Finish(*wire.CrawlInfo) error {
+	writer := csv.NewWriter(rank.out)
+	writer.Write(csvRankHeader)
+	for i, pRank := range Rank(rank.graph.sorted()) {
+		writer.Write([]string{
+			strconv.Itoa(i + 1),
+			pRank.URL,
+			strconv.FormatFloat(pRank.Rank, 'f', 6, 64),
+			strconv.Itoa(pRank.InDegree),
+			strconv.Itoa(pRank.OutDegree),
+			strconv.FormatFloat(pRank.Betweenness, 'f', 6, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Sizes and colors pages in the graph by their PageRank,
+// relative to the top ranked page.
+func (dot *dotPrinter) addRanks() error {
+	ranks := Rank(dot.graph.sorted())
+	for _, pRank := range ranks {
+		pageURL, err := url.Parse(pRank.URL)
+		if err != nil {
+			return err
+		}
+		scale := pRank.Rank / ranks[0].Rank
+		attrs := map[string]string{
+			"style":       "filled",
+			"colorscheme": "ylorrd9",
+			"fillcolor":   strconv.Itoa(1 + int(scale*8)),
+			"fontsize":    strconv.Itoa(10 + int(scale*20)),
+		}
+		if dot.options.Tooltip == "" {
+			attrs["tooltip"] = fmt.Sprintf("\"PageRank %.6f\"", pRank.Rank)
+		}
+		dot.addNode(fmt.Sprintf("%q", pRank.URL), pageURL, false, attrs)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package processor
+
+import (
+	wire "github.com/ronin13/dotler/wire"
+
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SitemapMaxURLs is the most URLs a single sitemap may have
+// as per https://www.sitemaps.org/protocol.html
+const SitemapMaxURLs = 50000
+
+const sitemapXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURL struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod,omitempty"`
+	Priority string `xml:"priority,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name       `xml:"sitemapindex"`
+	XMLNS    string         `xml:"xmlns,attr"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// ParseSitemap reads a sitemap or a sitemap index from in,
+// returning the urls of the former or the sitemaps of the latter.
+func ParseSitemap(in io.Reader) (urls []string, sitemaps []string, err error) {
+	var doc struct {
+		URLs     []sitemapURL   `xml:"url"`
+		Sitemaps []sitemapEntry `xml:"sitemap"`
+	}
+	if err = xml.NewDecoder(in).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	for _, entry := range doc.URLs {
+		urls = append(urls, strings.TrimSpace(entry.Loc))
+	}
+	for _, entry := range doc.Sitemaps {
+		sitemaps = append(sitemaps, strings.TrimSpace(entry.Loc))
+	}
+	return urls, sitemaps, nil
+}
+
+type sitemapPrinter struct {
+	pages []*wire.Page
+	// Links between pages, for the click depth of priority.
+	links    []Edge
+	create   func(string) (io.WriteCloser, error)
+	maxURLs  int
+	priority bool
+}
+
+// NewSitemap returns a GraphProcessor which writes sitemap.xml of the
+// successfully crawled pages, with files made by create.
+// With more than maxURLs pages (0 for SitemapMaxURLs), they are split
+// into sitemap-1.xml, sitemap-2.xml.. and sitemap.xml is their index.
+// If priority is set, it is derived from the click depth of each
+// page from the root url, see ClickDepths.
+func NewSitemap(create func(string) (io.WriteCloser, error), maxURLs int, priority bool) wire.GraphProcessor {
+	if maxURLs <= 0 || maxURLs > SitemapMaxURLs {
+		maxURLs = SitemapMaxURLs
+	}
+	return &sitemapPrinter{create: create, maxURLs: maxURLs, priority: priority}
+}
+
+// Only the fields needed later are kept, and the links if priority is set.
+func (smap *sitemapPrinter) ProcessPage(iPage *wire.Page) error {
+	if smap.priority {
+		presURL := iPage.PageURL.String()
+		for _, oPage := range iPage.OutLinks {
+			smap.links = append(smap.links, Edge{Source: presURL, Target: oPage.Page.PageURL.String(), Kind: EdgeLink})
+		}
+	}
+	if iPage.Status < 200 || iPage.Status > 299 {
+		return nil
+	}
+	smap.pages = append(smap.pages, &wire.Page{
+		PageURL:      iPage.PageURL,
+		LastModified: iPage.LastModified,
+	})
+	return nil
+}
+
+// 1.0 for the root, 0.2 less for every level down to 0.1.
+func depthPriority(depth int) string {
+	priority := 1.0 - 0.2*float64(depth)
+	if priority < 0.1 {
+		priority = 0.1
+	}
+	return strconv.FormatFloat(priority, 'f', 1, 64)
+}
+
+func (smap *sitemapPrinter) write(name string, doc interface{}) error {
+	file, err := smap.create(name)
+	if err != nil {
+		return err
+	}
+	if err = writeXML(file, doc); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (smap *sitemapPrinter) Finish(info *wire.CrawlInfo) error {
+	sort.Slice(smap.pages, func(i, j int) bool {
+		return smap.pages[i].PageURL.String() < smap.pages[j].PageURL.String()
+	})
+
+	var depths map[string]int
+	if smap.priority {
+		nodes := make([]Node, len(smap.pages))
+		for i, iPage := range smap.pages {
+			nodes[i] = Node{URL: iPage.PageURL.String(), Type: NodePage}
+		}
+		depths = ClickDepths(nodes, smap.links, info.RootURL)
+	}
+
+	var urlSets []sitemapURLSet
+	var lastMods []time.Time
+	for i, iPage := range smap.pages {
+		if i%smap.maxURLs == 0 {
+			urlSets = append(urlSets, sitemapURLSet{XMLNS: sitemapXMLNS})
+			lastMods = append(lastMods, time.Time{})
+		}
+		entry := sitemapURL{Loc: iPage.PageURL.String()}
+		if !iPage.LastModified.IsZero() {
+			entry.LastMod = iPage.LastModified.UTC().Format(time.RFC3339)
+			if iPage.LastModified.After(lastMods[len(lastMods)-1]) {
+				lastMods[len(lastMods)-1] = iPage.LastModified
+			}
+		}
+		if smap.priority {
+			// Pages ClickDepths can not reach from the root get the least.
+			depth, reached := depths[entry.Loc]
+			if !reached {
+				depth = math.MaxInt32
+			}
+			entry.Priority = depthPriority(depth)
+		}
+		urlSets[len(urlSets)-1].URLs = append(urlSets[len(urlSets)-1].URLs, entry)
+	}
+
+	if len(urlSets) <= 1 {
+		urlSet := sitemapURLSet{XMLNS: sitemapXMLNS}
+		if len(urlSets) == 1 {
+			urlSet = urlSets[0]
+		}
+		return smap.write("sitemap.xml", &urlSet)
+	}
+
+	// Sitemaps are expected next to the index at the root of the site.
+	rootURL, err := url.Parse(info.RootURL)
+	if err != nil {
+		return err
+	}
+	index := sitemapIndex{XMLNS: sitemapXMLNS}
+	for i := range urlSets {
+		name := fmt.Sprintf("sitemap-%d.xml", i+1)
+		if err = smap.write(name, &urlSets[i]); err != nil {
+			return err
+		}
+		entry := sitemapEntry{Loc: rootURL.ResolveReference(&url.URL{Path: "/" + name}).String()}
+		if !lastMods[i].IsZero() {
+			entry.LastMod = lastMods[i].UTC().Format(time.RFC3339)
+		}
+		index.Sitemaps = append(index.Sitemaps, entry)
+	}
+	return smap.write("sitemap.xml", &index)
+}
@@ -30,6 +30,10 @@ func TestDotlerCrawl(t *testing.T) {
 	nd := &NodeMap{}
 
 	flag.Lookup("alsologtostderr").Value.Set("false")
+	crawler, err := dotler.NewCrawler(dotler.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
 	var wg sync.WaitGroup
 	var reqChan, dotChan chan *wire.Page
 	testURLs := []struct {
@@ -47,7 +51,7 @@ func TestDotlerCrawl(t *testing.T) {
 		parsedURL, _ := url.Parse(urls.turl)
 		samplePage := &wire.Page{PageURL: parsedURL}
 		wg.Add(1)
-		dotler.Crawl(context.Background(), samplePage, reqChan, dotChan, &wg, nd)
+		crawler.Crawl(context.Background(), samplePage, reqChan, dotChan, &wg, nd)
 		wg.Wait()
 		if len(reqChan) != urls.linkCount {
 			t.Fatalf("Failed to crawl %s: %d %d", urls.turl, len(reqChan), urls.linkCount)
@@ -59,6 +63,10 @@ func BenchmarkCrawl(b *testing.B) {
 
 	nd := &NodeMap{}
 	flag.Lookup("alsologtostderr").Value.Set("false")
+	crawler, err := dotler.NewCrawler(dotler.DefaultConfig())
+	if err != nil {
+		b.Fatal(err)
+	}
 	reqChan := make([]chan *wire.Page, b.N)
 	dotChan := make([]chan *wire.Page, b.N)
 	bURL := "http://www.wnohang.net/pages/about/"
@@ -70,7 +78,7 @@ func BenchmarkCrawl(b *testing.B) {
 		parsedURL, _ := url.Parse(bURL)
 		samplePage := &wire.Page{PageURL: parsedURL}
 		wg.Add(1)
-		dotler.Crawl(context.Background(), samplePage, reqChan[n], dotChan[n], &wg, nd)
+		crawler.Crawl(context.Background(), samplePage, reqChan[n], dotChan[n], &wg, nd)
 		wg.Wait()
 	}
 }
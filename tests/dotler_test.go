@@ -1,8 +1,15 @@
 package dotler_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"flag"
 	"github.com/ronin13/dotler/dotler"
+	"github.com/ronin13/dotler/processor"
+	"github.com/ronin13/dotler/wire"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"testing"
 )
@@ -20,8 +27,10 @@ var urlTests = []struct {
 func TestDotler(t *testing.T) {
 	flag.Lookup("alsologtostderr").Value.Set("false")
 	for _, testURL := range urlTests {
-		dotler.ClientTimeout = testURL.clientTimeout
-		code := dotler.StartCrawl(testURL.turl)
+		config := dotler.DefaultConfig()
+		config.RootURL = testURL.turl
+		config.ClientTimeout = testURL.clientTimeout
+		code := dotler.StartCrawl(config, dotler.Options{})
 		if code != 0 {
 			t.Fatalf("Testing failed on %s", testURL.turl)
 		}
@@ -34,11 +43,244 @@ func TestDotler(t *testing.T) {
 	}
 }
 
+func TestCrawlerRun(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	site := newTestSite()
+	defer site.Close()
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.ClientTimeout = 10
+
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Crawling %s failed: %s", config.RootURL, err)
+	}
+	if result.Graph == "" || result.Stats.Success != uint64(len(testSite)) {
+		t.Fatalf("Crawling %s returned no graph: %+v", config.RootURL, result.Stats)
+	}
+	if _, err := os.Stat("dotler.dot"); !os.IsNotExist(err) {
+		t.Fatalf("Crawler wrote dotler.dot")
+	}
+}
+
+func TestRelativeLinks(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	pages := map[string]string{
+		"/":           `<html><body><a href="docs/guide">Guide</a></body></html>`,
+		"/docs/guide": `<html><body><a href="../">Up</a> <a href="/">Home</a> <a href="intro">Intro</a></body></html>`,
+		"/docs/intro": `<html><body><a href="guide">Guide</a> <a href="./intro">Self</a></body></html>`,
+	}
+	site := newSite(pages)
+	defer site.Close()
+
+	var out bytes.Buffer
+	config := dotler.DefaultConfig()
+	// Without the trailing /, the root is still recorded as the links to it.
+	config.RootURL = site.URL
+	config.Processors = map[string]wire.GraphProcessor{"json": processor.NewJSON(&out)}
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stats.Success != uint64(len(pages)) {
+		t.Fatalf("Expected %d pages crawled: %+v", len(pages), result.Stats)
+	}
+
+	var doc processor.JSONDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Nodes) != len(pages) || doc.Nodes[0].URL != site.URL+"/" || doc.Nodes[0].Status != http.StatusOK {
+		t.Fatalf("Root not recorded as %s/: %+v", site.URL, doc.Nodes)
+	}
+	links := make(map[string]uint)
+	for _, edge := range doc.Edges {
+		links[edge.Source[len(site.URL):]+" "+edge.Target[len(site.URL):]] = edge.Card
+	}
+	// ../ is the root, not the page it is on.
+	expected := map[string]uint{
+		"/ /docs/guide":           1,
+		"/docs/guide /":           2,
+		"/docs/guide /docs/intro": 1,
+		"/docs/intro /docs/guide": 1,
+		"/docs/intro /docs/intro": 1,
+	}
+	if len(links) != len(expected) {
+		t.Fatalf("Expected links %v, got %v", expected, links)
+	}
+	for link, card := range expected {
+		if links[link] != card {
+			t.Fatalf("Expected links %v, got %v", expected, links)
+		}
+	}
+}
+
+func TestCrawlerConfig(t *testing.T) {
+	config := dotler.DefaultConfig()
+	config.RootURL = "/relative"
+	if _, err := dotler.NewCrawler(config); err == nil {
+		t.Fatalf("Relative root url accepted")
+	}
+	config = dotler.DefaultConfig()
+	config.NodeStore = "tape"
+	if _, err := dotler.NewCrawler(config); err == nil {
+		t.Fatalf("Unknown node store accepted")
+	}
+}
+
+// Renders a crawl of the test site saved as JSON and dotler.dot
+// in a scratch directory, the working directory till done is called.
+// Returns the JSON of the crawl.
+func renderDir(t *testing.T) (saved []byte, done func()) {
+	var crawl, graph bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"json": processor.NewJSON(&crawl),
+		"dot":  processor.NewDotPrinter(&graph, processor.DotOptions{}),
+	})
+	dir, err := ioutil.TempDir("", "dotler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	done = func() {
+		os.Chdir(cwd)
+		os.RemoveAll(dir)
+	}
+	if err = ioutil.WriteFile("crawl.json", crawl.Bytes(), 0644); err != nil {
+		done()
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile("dotler.dot", graph.Bytes(), 0644); err != nil {
+		done()
+		t.Fatal(err)
+	}
+	return crawl.Bytes(), done
+}
+
+func readJSON(t *testing.T, name string) processor.JSONDocument {
+	rendered, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc processor.JSONDocument
+	if err = json.Unmarshal(rendered, &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestRenderCommand(t *testing.T) {
+	saved, done := renderDir(t)
+	defer done()
+
+	if code := dotler.RenderCommand([]string{"-output-format", "json", "crawl.json"}); code != 0 {
+		t.Fatalf("Render of crawl.json exited with %d", code)
+	}
+	var doc processor.JSONDocument
+	if err := json.Unmarshal(saved, &doc); err != nil {
+		t.Fatal(err)
+	}
+	again := readJSON(t, "dotler.json")
+	before, _ := json.Marshal([]interface{}{doc.Nodes, doc.Edges})
+	after, _ := json.Marshal([]interface{}{again.Nodes, again.Edges})
+	if !bytes.Equal(before, after) {
+		t.Fatalf("Rendering the saved crawl changed it:\n%s\n%s", before, after)
+	}
+
+	// dotler.dot is the input, and rewritten without assets.
+	if code := dotler.RenderCommand([]string{"-assets", "none", "-output-format", "json", "dotler.dot"}); code != 0 {
+		t.Fatalf("Render of dotler.dot exited with %d", code)
+	}
+	if doc = readJSON(t, "dotler.json"); len(doc.Nodes) != len(testSite) {
+		t.Fatalf("Expected the %d pages without assets, got %+v", len(testSite), doc.Nodes)
+	}
+	graph, err := ioutil.ReadFile("dotler.dot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(graph, []byte("/blog/first")) || bytes.Contains(graph, []byte("logo.png")) {
+		t.Fatalf("Expected dotler.dot without assets:\n%s", graph)
+	}
+}
+
+func TestRenderCommandErrors(t *testing.T) {
+	_, done := renderDir(t)
+	defer done()
+
+	if code := dotler.RenderCommand([]string{"-assets", "some", "crawl.json"}); code != 2 {
+		t.Fatalf("Expected exit code 2 for an invalid -assets, got %d", code)
+	}
+	if code := dotler.RenderCommand([]string{"missing.json"}); code != 2 {
+		t.Fatalf("Expected exit code 2 for a missing crawl, got %d", code)
+	}
+	for _, format := range dotler.JSONOnlyFormats {
+		if code := dotler.RenderCommand([]string{"-output-format", format, "dotler.dot"}); code != 2 {
+			t.Fatalf("Expected exit code 2 for %s of dotler.dot, got %d", format, code)
+		}
+		if code := dotler.RenderCommand([]string{"-gen-graph=false", "-output-format", format, "crawl.json"}); code != 0 {
+			t.Fatalf("Render of %s of crawl.json exited with %d", format, code)
+		}
+	}
+	if err := os.Remove("dotler.dot"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("dotler.dot", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if code := dotler.RenderCommand([]string{"crawl.json"}); code != 1 {
+		t.Fatalf("Expected exit code 1 when dotler.dot can not be written, got %d", code)
+	}
+
+	site := newTestSite()
+	defer site.Close()
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	if code := dotler.StartCrawl(config, dotler.Options{}); code != 1 {
+		t.Fatalf("Expected exit code 1 when the crawl can not write dotler.dot, got %d", code)
+	}
+}
+
+func TestPathCommand(t *testing.T) {
+	saved, done := renderDir(t)
+	defer done()
+	var doc processor.JSONDocument
+	if err := json.Unmarshal(saved, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if code := dotler.PathCommand([]string{"-to", doc.RootURL + "blog/first", "crawl.json"}, &out); code != 0 {
+		t.Fatalf("Path of crawl.json exited with %d", code)
+	}
+	if expected := doc.RootURL + " -> " + doc.RootURL + "blog -> " + doc.RootURL + "blog/first\n"; out.String() != expected {
+		t.Fatalf("Expected path %q, got %q", expected, out.String())
+	}
+	if code := dotler.PathCommand([]string{"-to", doc.RootURL + "blog", "-format", "svg", "crawl.json"}, &out); code != 2 {
+		t.Fatalf("Expected exit code 2 for -format without -dot, got %d", code)
+	}
+}
+
 func BenchmarkDotler(b *testing.B) {
 	flag.Lookup("alsologtostderr").Value.Set("false")
+	config := dotler.DefaultConfig()
+	config.RootURL = "http://wnohang.net"
+	config.ClientTimeout = 1
 	for n := 0; n < b.N; n++ {
-		dotler.ClientTimeout = 1
-		code := dotler.StartCrawl("http://wnohang.net")
+		code := dotler.StartCrawl(config, dotler.Options{})
 		if code > 0 {
 			b.Fatalf("Benchmark failed")
 		}
@@ -47,10 +289,12 @@ func BenchmarkDotler(b *testing.B) {
 
 func BenchmarkDotlerWithoutGen(b *testing.B) {
 	flag.Lookup("alsologtostderr").Value.Set("false")
-	flag.Lookup("gen-graph").Value.Set("true")
-	dotler.ClientTimeout = 1
+	config := dotler.DefaultConfig()
+	config.RootURL = "http://wnohang.net"
+	config.ClientTimeout = 1
+	config.GenGraph = false
 	for n := 0; n < b.N; n++ {
-		code := dotler.StartCrawl("http://wnohang.net")
+		code := dotler.StartCrawl(config, dotler.Options{})
 		if code > 0 {
 			b.Fatalf("Benchmark failed")
 		}
@@ -59,6 +303,6 @@ func BenchmarkDotlerWithoutGen(b *testing.B) {
 
 func TestMain(m *testing.M) {
 	os.Remove("dotler.dot")
-	dotler.ParseFlags()
+	flag.Parse()
 	os.Exit(m.Run())
 }
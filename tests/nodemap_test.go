@@ -0,0 +1,307 @@
+package dotler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ronin13/dotler/dotler"
+	"github.com/ronin13/dotler/processor"
+	"github.com/ronin13/dotler/wire"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const (
+	benchPages    = 20000
+	benchOutLinks = 20
+	benchStatics  = 10
+)
+
+// Builds a synthetic site of benchPages pages, each linking to
+// benchOutLinks other pages and benchStatics static assets.
+func fillNodes(b *testing.B, nodes wire.NodeMapper) {
+	pages := make([]*wire.Page, benchPages)
+	for i := range pages {
+		pageURL, _ := url.Parse(fmt.Sprintf("http://example.com/page/%d", i))
+		pages[i] = &wire.Page{PageURL: pageURL}
+	}
+	spill, spills := nodes.(wire.Spiller)
+	for i, page := range pages {
+		if err := nodes.Add(page.PageURL.String(), page); err != nil {
+			b.Fatal(err)
+		}
+		page.OutLinks = make(map[string]*wire.PageWithCard, benchOutLinks)
+		page.StatList = make(map[string]wire.StatPage, benchStatics)
+		for j := 1; j <= benchOutLinks; j++ {
+			oPage := pages[(i+j)%benchPages]
+			page.OutLinks[oPage.PageURL.String()] = &wire.PageWithCard{Page: oPage, Card: uint(j)}
+		}
+		for j := 0; j < benchStatics; j++ {
+			statURL, _ := url.Parse(fmt.Sprintf("http://example.com/static/%d/%d.js", i, j))
+			page.StatList[statURL.String()] = wire.StatPage{PageTitle: fmt.Sprintf("%d.js", j), StaticURL: statURL}
+		}
+		if spills {
+			if err := spill.Spill(page.PageURL.String()); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse
+}
+
+func benchNodeMapper(b *testing.B, newMapper func() (wire.NodeMapper, context.CancelFunc)) {
+	var heap uint64
+	for n := 0; n < b.N; n++ {
+		before := heapInUse()
+		nodes, cFunc := newMapper()
+		fillNodes(b, nodes)
+		heap += heapInUse() - before
+		runtime.KeepAlive(nodes)
+		cFunc()
+	}
+	b.ReportMetric(float64(heap)/float64(b.N)/(1<<20), "heap-MB/op")
+}
+
+func BenchmarkNodeMapMemory(b *testing.B) {
+	benchNodeMapper(b, func() (wire.NodeMapper, context.CancelFunc) {
+		return wire.NewNodeMapper(context.Background())
+	})
+}
+
+func BenchmarkBoltMapMemory(b *testing.B) {
+	dir, err := ioutil.TempDir("", "dotler")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	benchNodeMapper(b, func() (wire.NodeMapper, context.CancelFunc) {
+		nodes, cFunc, err := wire.NewBoltMapper(filepath.Join(dir, "dotler.db"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		return nodes, cFunc
+	})
+}
+
+func TestBoltMapSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dotler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nodes, cFunc, err := wire.NewBoltMapper(filepath.Join(dir, "dotler.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cFunc()
+
+	rootURL, _ := url.Parse("http://example.com/")
+	aboutURL, _ := url.Parse("http://example.com/about")
+	statURL, _ := url.Parse("http://example.com/main.css")
+	about := &wire.Page{PageURL: aboutURL}
+	root := &wire.Page{
+		PageURL:  rootURL,
+		OutLinks: map[string]*wire.PageWithCard{aboutURL.String(): {Page: about, Card: 3, Links: []wire.Link{{Text: "About", Region: "nav"}}}},
+		StatList: map[string]wire.StatPage{statURL.String(): {PageTitle: "main.css", StaticURL: statURL}},
+		Anchors:  []string{"top"},
+		Headers:  map[string]string{"X-Frame-Options": "DENY"},
+		Cookies:  []wire.Cookie{{Name: "session", Secure: true}},
+	}
+	if err := nodes.Add(rootURL.String(), root); err != nil {
+		t.Fatal(err)
+	}
+	if err := nodes.(wire.Spiller).Spill(rootURL.String()); err != nil {
+		t.Fatal(err)
+	}
+	if root.OutLinks != nil || root.StatList != nil || root.Anchors != nil || root.Headers != nil || root.Cookies != nil {
+		t.Fatalf("Spilled page still holds its links: %+v", root)
+	}
+	if nodes.Add("https://example.com/", root) == nil {
+		t.Fatalf("Spilled page was added twice")
+	}
+	if page := nodes.Exists(rootURL.String()); page == nil || page.PageURL.String() != rootURL.String() {
+		t.Fatalf("Spilled page not found: %v", page)
+	}
+
+	loaded, err := nodes.(*wire.BoltMap).Load(rootURL.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if oPage, exists := loaded.OutLinks[aboutURL.String()]; !exists || oPage.Card != 3 || oPage.Anchor() != "About" {
+		t.Fatalf("Out links not restored: %v", loaded.OutLinks)
+	}
+	if sPage, exists := loaded.StatList[statURL.String()]; !exists || sPage.PageTitle != "main.css" {
+		t.Fatalf("Static list not restored: %v", loaded.StatList)
+	}
+	if len(loaded.Anchors) != 1 || loaded.Headers["X-Frame-Options"] != "DENY" || len(loaded.Cookies) != 1 || !loaded.Cookies[0].Secure {
+		t.Fatalf("Page metadata not restored: %+v", loaded)
+	}
+}
+
+// Crawls pages, keyed by path, keeping them in store, memory or bolt,
+// with static assets as in mode.
+func crawlStore(t testing.TB, pages map[string]string, store, mode string, procs map[string]wire.GraphProcessor) *dotler.Result {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	site := newSite(pages)
+	defer site.Close()
+	dir, err := ioutil.TempDir("", "dotler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.NodeStore = store
+	config.NodeStorePath = filepath.Join(dir, "dotler.db")
+	config.Assets.Mode = mode
+	config.Processors = procs
+	// The graph would hold every page.
+	config.GenGraph = false
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestBoltSharedAssets(t *testing.T) {
+	var memory, bolt bytes.Buffer
+	crawlStore(t, testSite, "memory", processor.AssetsShared, map[string]wire.GraphProcessor{"json": processor.NewJSON(&memory)})
+	crawlStore(t, testSite, "bolt", processor.AssetsShared, map[string]wire.GraphProcessor{"json": processor.NewJSON(&bolt)})
+
+	var memoryDoc, boltDoc processor.JSONDocument
+	if err := json.Unmarshal(memory.Bytes(), &memoryDoc); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(bolt.Bytes(), &boltDoc); err != nil {
+		t.Fatal(err)
+	}
+	// The sites differ only by their port.
+	memoryGraph, _ := json.Marshal([]interface{}{memoryDoc.Edges, memoryDoc.Nodes})
+	boltGraph, _ := json.Marshal([]interface{}{boltDoc.Edges, boltDoc.Nodes})
+	if strings.Replace(string(memoryGraph), memoryDoc.RootURL, "", -1) != strings.Replace(string(boltGraph), boltDoc.RootURL, "", -1) {
+		t.Fatalf("Pages loaded back from bolt differ:\n%s\n%s", memoryGraph, boltGraph)
+	}
+	// logo.png is the only asset of more than one page.
+	if len(boltDoc.Nodes) != len(testSite)+1 {
+		t.Fatalf("Expected the pages and a shared asset: %+v", boltDoc.Nodes)
+	}
+}
+
+// Keeps every page it is fed.
+type retainingProcessor struct {
+	pages []*wire.Page
+}
+
+func (retain *retainingProcessor) ProcessPage(page *wire.Page) error {
+	retain.pages = append(retain.pages, page)
+	return nil
+}
+
+func (retain *retainingProcessor) Finish(*wire.CrawlInfo) error {
+	return nil
+}
+
+// Records the heap in use at Finish, while the
+// crawler still holds its node map.
+type heapProcessor struct {
+	heap uint64
+}
+
+func (heap *heapProcessor) ProcessPage(page *wire.Page) error {
+	return nil
+}
+
+func (heap *heapProcessor) Finish(*wire.CrawlInfo) error {
+	heap.heap = heapInUse()
+	return nil
+}
+
+func TestBoltCrawlReleasesPages(t *testing.T) {
+	for _, store := range []string{"memory", "bolt"} {
+		retain := &retainingProcessor{}
+		result := crawlStore(t, testSite, store, processor.AssetsFull, map[string]wire.GraphProcessor{"retain": retain})
+		if len(retain.pages) != len(testSite) || result.Stats.Success != uint64(len(testSite)) {
+			t.Fatalf("Expected %d pages crawled with %s: %d %+v", len(testSite), store, len(retain.pages), result.Stats)
+		}
+		for _, page := range retain.pages {
+			released := page.OutLinks == nil && page.StatList == nil && page.Headers == nil
+			if released != (store == "bolt") {
+				t.Fatalf("Expected the links of %s released only with bolt, %s: %+v", page.PageURL, store, page)
+			}
+		}
+	}
+}
+
+// A site of crawlPages pages, each linking to crawlOutLinks
+// other pages and benchStatics static assets of its own.
+const (
+	crawlPages    = 500
+	crawlOutLinks = 20
+)
+
+func generatedSite() map[string]string {
+	path := func(i int) string {
+		if i == 0 {
+			return "/"
+		}
+		return fmt.Sprintf("/page/%d", i)
+	}
+	pages := make(map[string]string, crawlPages)
+	for i := 0; i < crawlPages; i++ {
+		var body bytes.Buffer
+		fmt.Fprintf(&body, "<html><head><title>Page %d</title></head><body>", i)
+		for j := 1; j <= crawlOutLinks; j++ {
+			fmt.Fprintf(&body, `<a href="%s">Page %d</a> `, path((i+j)%crawlPages), (i+j)%crawlPages)
+		}
+		for j := 0; j < benchStatics; j++ {
+			fmt.Fprintf(&body, `<script src="/static/%d/%d.js"></script>`, i, j)
+		}
+		body.WriteString("</body></html>")
+		pages[path(i)] = body.String()
+	}
+	return pages
+}
+
+func benchCrawl(b *testing.B, store string) {
+	pages := generatedSite()
+	var heap uint64
+	for n := 0; n < b.N; n++ {
+		before := heapInUse()
+		heapProc := &heapProcessor{}
+		result := crawlStore(b, pages, store, processor.AssetsFull, map[string]wire.GraphProcessor{"heap": heapProc})
+		if result.Stats.Success != crawlPages {
+			b.Fatalf("Expected %d pages crawled: %+v", crawlPages, result.Stats)
+		}
+		heap += heapProc.heap - before
+	}
+	b.ReportMetric(float64(heap)/float64(b.N)/(1<<20), "heap-MB/op")
+}
+
+// The heap in use at the end of a crawl, with the node map.
+func BenchmarkCrawlNodeMapMemory(b *testing.B) {
+	benchCrawl(b, "memory")
+}
+
+func BenchmarkCrawlBoltMapMemory(b *testing.B) {
+	benchCrawl(b, "bolt")
+}
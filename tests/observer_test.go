@@ -0,0 +1,156 @@
+package dotler_test
+
+import (
+	"context"
+	"flag"
+	"github.com/ronin13/dotler/dotler"
+	"github.com/ronin13/dotler/wire"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type recordingObserver struct {
+	dotler.BaseObserver
+	sync.Mutex
+	fetched  map[string]bool
+	links    int
+	dropped  map[string]dotler.DropReason
+	finished *dotler.Result
+}
+
+func (observer *recordingObserver) PageFetched(page *wire.Page) {
+	observer.Lock()
+	defer observer.Unlock()
+	observer.fetched[page.PageURL.Path] = true
+}
+
+func (observer *recordingObserver) LinkDiscovered(from, to *wire.Page) {
+	observer.Lock()
+	defer observer.Unlock()
+	observer.links++
+}
+
+func (observer *recordingObserver) PageDropped(page *wire.Page, reason dotler.DropReason) {
+	observer.Lock()
+	defer observer.Unlock()
+	observer.dropped[page.PageURL.Path] = reason
+}
+
+func (observer *recordingObserver) CrawlFinished(result *dotler.Result) {
+	observer.Lock()
+	defer observer.Unlock()
+	observer.finished = result
+}
+
+func TestObserver(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	site := newTestSite()
+	defer site.Close()
+	testSite["/missing"] = `<html><body><a href="/gone">Gone</a></body></html>`
+	defer delete(testSite, "/missing")
+
+	observer := &recordingObserver{
+		fetched: make(map[string]bool),
+		dropped: make(map[string]dotler.DropReason),
+	}
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/missing"
+	config.Observer = observer
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !observer.fetched["/missing"] || observer.links != 1 {
+		t.Fatalf("Fetch events missing: %v %d", observer.fetched, observer.links)
+	}
+	if observer.finished != result {
+		t.Fatalf("Finish event missing")
+	}
+
+	// Nothing is listening anymore, fetch fails.
+	site.Close()
+	if _, err = crawler.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if reason, exists := observer.dropped["/missing"]; !exists || reason != dotler.Failed {
+		t.Fatalf("Drop event missing: %v", observer.dropped)
+	}
+}
+
+func TestObserverDrops(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	pages := map[string]string{
+		"/":     `<html><body><a href="/slow">Slow</a></body></html>`,
+		"/hang": `<html><body><a href="/slow">Slow</a></body></html>`,
+		"/slow": `<html><body>Slow</body></html>`,
+	}
+	handler := siteHandler(pages)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Hangs /slow and /hang till the test is over.
+	release := make(chan struct{})
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			// Past Config.CrawlThreshold, till the crawler gives up.
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		case "/hang":
+			cancel()
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer site.Close()
+	defer close(release)
+
+	for _, test := range []struct {
+		root    string
+		ctx     context.Context
+		dropped string
+		reason  dotler.DropReason
+		stats   dotler.Stats
+	}{
+		{"/", context.Background(), "/slow", dotler.Skipped, dotler.Stats{Success: 1, Skipped: 1}},
+		// The crawl is cancelled while /hang is fetched.
+		{"/hang", ctx, "/hang", dotler.Cancelled, dotler.Stats{Cancelled: 1}},
+	} {
+		observer := &recordingObserver{
+			fetched: make(map[string]bool),
+			dropped: make(map[string]dotler.DropReason),
+		}
+		config := dotler.DefaultConfig()
+		config.RootURL = site.URL + test.root
+		config.CrawlThreshold = 1
+		config.Observer = observer
+		crawler, err := dotler.NewCrawler(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := crawler.Run(test.ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if reason, exists := observer.dropped[test.dropped]; !exists || reason != test.reason || len(observer.dropped) != 1 {
+			t.Fatalf("Expected %s %s, got %v", test.dropped, test.reason, observer.dropped)
+		}
+		if observer.finished != result || observer.finished.Stats != test.stats {
+			t.Fatalf("Expected the final stats %+v on finish, got %+v", test.stats, observer.finished)
+		}
+	}
+}
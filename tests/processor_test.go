@@ -0,0 +1,1111 @@
+package dotler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"github.com/ronin13/dotler/dotler"
+	"github.com/ronin13/dotler/processor"
+	"github.com/ronin13/dotler/wire"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingProcessor struct {
+	pages    int
+	links    uint
+	finished bool
+}
+
+func (count *countingProcessor) ProcessPage(page *wire.Page) error {
+	count.pages++
+	for _, oPage := range page.OutLinks {
+		count.links += oPage.Card
+	}
+	return nil
+}
+
+func (count *countingProcessor) Finish(*wire.CrawlInfo) error {
+	count.finished = true
+	return nil
+}
+
+type failingProcessor struct {
+	pages int
+}
+
+func (fail *failingProcessor) ProcessPage(page *wire.Page) error {
+	fail.pages++
+	return errors.New("disk full")
+}
+
+func (fail *failingProcessor) Finish(*wire.CrawlInfo) error {
+	return nil
+}
+
+// Crawls the local test site with procs attached.
+func crawlTestSite(t *testing.T, procs map[string]wire.GraphProcessor) *dotler.Result {
+	return crawlSite(t, testSite, procs)
+}
+
+// Crawls pages, keyed by path, served locally with procs attached.
+func crawlSite(t *testing.T, pages map[string]string, procs map[string]wire.GraphProcessor) *dotler.Result {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	site := newSite(pages)
+	defer site.Close()
+
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.Processors = procs
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestProcessorFanOut(t *testing.T) {
+	count := &countingProcessor{}
+	fail := &failingProcessor{}
+	result := crawlTestSite(t, map[string]wire.GraphProcessor{
+		"count": count,
+		"fail":  fail,
+	})
+
+	if count.pages != len(testSite) || count.links != 7 || !count.finished {
+		t.Fatalf("Counting processor missed pages: %+v", count)
+	}
+	if fail.pages != 1 {
+		t.Fatalf("Failed processor was fed %d pages", fail.pages)
+	}
+	if len(result.ProcessorErrors) != 1 || result.ProcessorErrors["fail"] == nil {
+		t.Fatalf("Processor errors not reported: %v", result.ProcessorErrors)
+	}
+	if result.Graph == "" {
+		t.Fatalf("Graph missing along with other processors")
+	}
+}
+
+func TestJSONExport(t *testing.T) {
+	var out bytes.Buffer
+	result := crawlTestSite(t, map[string]wire.GraphProcessor{
+		"json": processor.NewJSON(&out),
+	})
+
+	var doc processor.JSONDocument
+	if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != processor.JSONVersion || doc.RootURL != result.RootURL || doc.Statistics.Success != result.Stats.Success {
+		t.Fatalf("Bad header: %+v", doc)
+	}
+
+	nodes := make(map[string]processor.Node)
+	for _, node := range doc.Nodes {
+		nodes[strings.TrimPrefix(node.URL, doc.RootURL)] = node
+	}
+	if about := nodes["about"]; about.Title != "About" || about.Status != 200 || about.Depth != 1 || about.Type != processor.NodePage {
+		t.Fatalf("Bad page node: %+v", about)
+	}
+	if home := nodes[""]; home.Description != "The home page" || home.H1 != "Welcome home" || home.Lang != "en" || home.WordCount != 6 || len(home.ContentHash) != 64 || home.SimHash == "" {
+		t.Fatalf("Bad page metadata: %+v", home)
+	}
+	if logo := nodes["logo.png"]; logo.Title != "logo.png" || logo.Type != processor.NodeAsset {
+		t.Fatalf("Bad asset node: %+v", logo)
+	}
+	if len(doc.Nodes) != 7 || len(doc.Edges) != 10 {
+		t.Fatalf("Expected 7 nodes and 10 edges, got %d %d", len(doc.Nodes), len(doc.Edges))
+	}
+	for _, edge := range doc.Edges {
+		if edge.Source == doc.RootURL && edge.Target == doc.RootURL+"about" && edge.Card != 2 {
+			t.Fatalf("Bad edge cardinality: %+v", edge)
+		}
+		if edge.Source == doc.RootURL && edge.Target == doc.RootURL+"about" && (edge.Anchor != "About" || len(edge.Links) != 2 ||
+			edge.Links[0].Text != "About" || edge.Links[0].Title != "About this site" || edge.Links[0].Region != "nav" ||
+			edge.Links[1].Text != "About us" || edge.Links[1].Region != "") {
+			t.Fatalf("Bad links: %+v", edge)
+		}
+		if edge.Target == doc.RootURL+"blog" && edge.Source == doc.RootURL && (len(edge.Links) != 1 || strings.Join(edge.Links[0].Rel, " ") != "next") {
+			t.Fatalf("Bad rel of links: %+v", edge)
+		}
+	}
+}
+
+func TestJSONClickDepth(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	// /target is first found over the long path, as /slow is slow.
+	pages := map[string]string{
+		"/":       `<html><body><a href="/slow">Slow</a> <a href="/fast">Fast</a></body></html>`,
+		"/slow":   `<html><body><a href="/target">Target</a></body></html>`,
+		"/fast":   `<html><body><a href="/faster">Faster</a></body></html>`,
+		"/faster": `<html><body><a href="/target">Target</a><img src="/logo.png"></body></html>`,
+		"/target": `<html><body><img src="/logo.png"></body></html>`,
+	}
+	handler := siteHandler(pages)
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(300 * time.Millisecond)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer site.Close()
+
+	var out bytes.Buffer
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.Processors = map[string]wire.GraphProcessor{"json": processor.NewJSON(&out)}
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = crawler.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc processor.JSONDocument
+	if err = json.Unmarshal(out.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	depths := make(map[string]uint)
+	for _, node := range doc.Nodes {
+		depths[strings.TrimPrefix(node.URL, site.URL)] = node.Depth
+	}
+	expected := map[string]uint{"/": 0, "/slow": 1, "/fast": 1, "/faster": 2, "/target": 2, "/logo.png": 3}
+	for path, depth := range expected {
+		if depths[path] != depth {
+			t.Fatalf("Expected click depths %v, got %v", expected, depths)
+		}
+	}
+}
+
+func TestXMLExports(t *testing.T) {
+	var graphML, gexf bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"graphml": processor.NewGraphML(&graphML),
+		"gexf":    processor.NewGEXF(&gexf),
+	})
+
+	var gmlDoc struct {
+		Nodes []struct {
+			ID string `xml:"id,attr"`
+		} `xml:"graph>node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Data   []struct {
+				Key   string `xml:"key,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"data"`
+		} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(graphML.Bytes(), &gmlDoc); err != nil {
+		t.Fatal(err)
+	}
+	if len(gmlDoc.Nodes) != 7 || len(gmlDoc.Edges) != 10 {
+		t.Fatalf("Expected 7 nodes and 10 edges in graphml, got %d %d", len(gmlDoc.Nodes), len(gmlDoc.Edges))
+	}
+
+	var gexfDoc struct {
+		Nodes []struct {
+			Label string `xml:"label,attr"`
+		} `xml:"graph>nodes>node"`
+		Edges []struct {
+			Weight uint `xml:"weight,attr"`
+		} `xml:"graph>edges>edge"`
+	}
+	if err := xml.Unmarshal(gexf.Bytes(), &gexfDoc); err != nil {
+		t.Fatal(err)
+	}
+	if len(gexfDoc.Nodes) != 7 || len(gexfDoc.Edges) != 10 {
+		t.Fatalf("Expected 7 nodes and 10 edges in gexf, got %d %d", len(gexfDoc.Nodes), len(gexfDoc.Edges))
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	var nodes, edges bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"csv": processor.NewCSV(&nodes, &edges),
+	})
+
+	nodeRows, err := csv.NewReader(&nodes).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edgeRows, err := csv.NewReader(&edges).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodeRows) != 8 || len(edgeRows) != 11 {
+		t.Fatalf("Expected 7 nodes and 10 edges with headers, got %d %d", len(nodeRows), len(edgeRows))
+	}
+	if strings.Join(nodeRows[0], ",") != "url,type,title,status,description,h1,lang,word_count" || strings.Join(edgeRows[0], ",") != "source,target,card,kind,anchor,rel,regions" {
+		t.Fatalf("Bad headers: %v %v", nodeRows[0], edgeRows[0])
+	}
+	seen := make(map[string]bool)
+	for _, row := range nodeRows[1:] {
+		if seen[row[0]] {
+			t.Fatalf("Node %s written twice", row[0])
+		}
+		seen[row[0]] = true
+	}
+}
+
+// Collects the files written by a processor in memory.
+type memFiles map[string]*bytes.Buffer
+
+type memFile struct {
+	*bytes.Buffer
+}
+
+func (memFile) Close() error {
+	return nil
+}
+
+func (files memFiles) create(name string) (io.WriteCloser, error) {
+	files[name] = &bytes.Buffer{}
+	return memFile{files[name]}, nil
+}
+
+func TestSitemap(t *testing.T) {
+	files := memFiles{}
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"sitemap": processor.NewSitemap(files.create, 0, true),
+	})
+
+	var urlSet struct {
+		URLs []struct {
+			Loc      string `xml:"loc"`
+			LastMod  string `xml:"lastmod"`
+			Priority string `xml:"priority"`
+		} `xml:"url"`
+	}
+	if err := xml.Unmarshal(files["sitemap.xml"].Bytes(), &urlSet); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || len(urlSet.URLs) != len(testSite) {
+		t.Fatalf("Expected one sitemap of %d urls: %d %d", len(testSite), len(files), len(urlSet.URLs))
+	}
+	for _, entry := range urlSet.URLs {
+		if strings.HasSuffix(entry.Loc, "/blog/first") && entry.Priority != "0.6" {
+			t.Fatalf("Bad priority: %+v", entry)
+		}
+		if entry.LastMod != "2017-01-02T15:04:05Z" {
+			t.Fatalf("Bad lastmod: %+v", entry)
+		}
+	}
+}
+
+// A page at the discovery depth, linking to links relative to it.
+func linkedPage(t *testing.T, link string, depth uint, links ...string) *wire.Page {
+	iPage := assetPage(t, link)
+	iPage.Depth = depth
+	iPage.OutLinks = make(map[string]*wire.PageWithCard)
+	for _, out := range links {
+		parsed, _ := iPage.PageURL.Parse(out)
+		iPage.OutLinks[parsed.String()] = &wire.PageWithCard{Page: &wire.Page{PageURL: parsed}, Card: 1}
+	}
+	return iPage
+}
+
+func TestSitemapClickDepth(t *testing.T) {
+	files := memFiles{}
+	sitemap := processor.NewSitemap(files.create, 0, true)
+	// b was first found through a, deeper than the link from the root.
+	for _, iPage := range []*wire.Page{
+		linkedPage(t, "http://example.com/", 0, "a", "b"),
+		linkedPage(t, "http://example.com/a", 1, "b"),
+		linkedPage(t, "http://example.com/b", 2),
+		linkedPage(t, "http://example.com/seeded", 0),
+	} {
+		sitemap.ProcessPage(iPage)
+	}
+	if err := sitemap.Finish(&wire.CrawlInfo{RootURL: "http://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var urlSet struct {
+		URLs []struct {
+			Loc      string `xml:"loc"`
+			Priority string `xml:"priority"`
+		} `xml:"url"`
+	}
+	if err := xml.Unmarshal(files["sitemap.xml"].Bytes(), &urlSet); err != nil {
+		t.Fatal(err)
+	}
+	var priorities []string
+	for _, entry := range urlSet.URLs {
+		priorities = append(priorities, entry.Loc+" "+entry.Priority)
+	}
+	expected := "http://example.com/ 1.0,http://example.com/a 0.8,http://example.com/b 0.8,http://example.com/seeded 0.1"
+	if strings.Join(priorities, ",") != expected {
+		t.Fatalf("Expected priorities %s, got %s", expected, strings.Join(priorities, ","))
+	}
+}
+
+func TestSitemapIndex(t *testing.T) {
+	files := memFiles{}
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"sitemap": processor.NewSitemap(files.create, 3, false),
+	})
+
+	var index struct {
+		Sitemaps []struct {
+			Loc string `xml:"loc"`
+		} `xml:"sitemap"`
+	}
+	if err := xml.Unmarshal(files["sitemap.xml"].Bytes(), &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || len(index.Sitemaps) != 2 || !strings.HasSuffix(index.Sitemaps[1].Loc, "/sitemap-2.xml") {
+		t.Fatalf("Expected an index of 2 sitemaps: %v %+v", len(files), index)
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	var full, capped, uml bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"full":     processor.NewMermaid(&full, processor.DiagramOptions{}),
+		"capped":   processor.NewMermaid(&capped, processor.DiagramOptions{NoAssets: true, MaxNodes: 2}),
+		"plantuml": processor.NewPlantUML(&uml, processor.DiagramOptions{}),
+	})
+
+	if !strings.HasPrefix(full.String(), "graph LR\n") || !strings.Contains(full.String(), `["/blog/first"]`) || !strings.Contains(full.String(), "-.->") {
+		t.Fatalf("Bad mermaid diagram:\n%s", full.String())
+	}
+	if strings.Contains(capped.String(), "logo.png") || !strings.Contains(capped.String(), `more["… 2 more"]`) || !strings.Contains(capped.String(), "n0 --> more") {
+		t.Fatalf("Bad capped mermaid diagram:\n%s", capped.String())
+	}
+	if !strings.HasPrefix(uml.String(), "@startuml\n") || !strings.Contains(uml.String(), `file "/logo.png"`) {
+		t.Fatalf("Bad plantuml diagram:\n%s", uml.String())
+	}
+}
+
+func TestMermaidClickDepth(t *testing.T) {
+	var out bytes.Buffer
+	mermaid := processor.NewMermaid(&out, processor.DiagramOptions{NoAssets: true, MaxNodes: 2})
+	// z was first found deeper than a, but is fewer clicks from the root.
+	for _, iPage := range []*wire.Page{
+		linkedPage(t, "http://example.com/", 0, "z"),
+		linkedPage(t, "http://example.com/z", 3, "a"),
+		linkedPage(t, "http://example.com/a", 1),
+	} {
+		mermaid.ProcessPage(iPage)
+	}
+	if err := mermaid.Finish(&wire.CrawlInfo{RootURL: "http://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `n0["/"]`) || !strings.Contains(out.String(), `n1["/z"]`) || !strings.Contains(out.String(), `more["… 1 more"]`) {
+		t.Fatalf("Expected / and /z drawn and /a collapsed:\n%s", out.String())
+	}
+}
+
+func TestHTMLReport(t *testing.T) {
+	var out bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{"html": processor.NewHTML(&out)})
+
+	report := out.String()
+	if strings.Contains(report, "<script src") || strings.Contains(report, "<link") {
+		t.Fatalf("HTML report is not self-contained")
+	}
+	start := strings.Index(report, "const doc = ")
+	end := strings.Index(report, ";\n(function")
+	if start < 0 || end < start {
+		t.Fatalf("No graph in HTML report")
+	}
+	var doc processor.JSONDocument
+	if err := json.Unmarshal([]byte(report[start+len("const doc = "):end]), &doc); err != nil {
+		t.Fatalf("Failed to decode graph of HTML report: %s", err)
+	}
+	if len(doc.Nodes) != 7 || len(doc.Edges) != 10 || doc.Statistics.Success != 4 {
+		t.Errorf("Expected 7 nodes, 10 edges and 4 pages, got %d, %d and %d", len(doc.Nodes), len(doc.Edges), doc.Statistics.Success)
+	}
+}
+
+func TestDotClusters(t *testing.T) {
+	var clustered, collapsed bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"clustered": processor.NewDotPrinter(&clustered, processor.DotOptions{ClusterDepth: 2}),
+		"collapsed": processor.NewDotPrinter(&collapsed, processor.DotOptions{ClusterDepth: 2, Collapse: true}),
+	})
+
+	if strings.Count(clustered.String(), "subgraph cluster_") != 1 || !strings.Contains(clustered.String(), `label="/blog/"`) {
+		t.Fatalf("Expected a single /blog/ cluster:\n%s", clustered.String())
+	}
+	if strings.Contains(collapsed.String(), "subgraph") || strings.Contains(collapsed.String(), "/blog/first") ||
+		!strings.Contains(collapsed.String(), `"/blog/\n2 pages"`) {
+		t.Fatalf("Expected /blog/ collapsed into a node:\n%s", collapsed.String())
+	}
+}
+
+func assetPage(t *testing.T, link string, assets ...string) *wire.Page {
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iPage := &wire.Page{PageURL: parsed, Status: 200, StatList: make(map[string]wire.StatPage)}
+	for _, asset := range assets {
+		parsedAsset, _ := parsed.Parse(asset)
+		iPage.StatList[parsedAsset.String()] = wire.StatPage{PageTitle: asset, StaticURL: parsedAsset}
+	}
+	return iPage
+}
+
+func TestAssetModes(t *testing.T) {
+	expected := map[string][]string{
+		processor.AssetsFull:      {"http://example.com/a", "http://example.com/app.js", "http://example.com/b", "http://example.com/logo.png", "http://example.com/main.css"},
+		processor.AssetsNone:      {"http://example.com/a", "http://example.com/b"},
+		processor.AssetsAggregate: {"http://example.com/a", "http://example.com/a#assets", "http://example.com/b", "http://example.com/b#assets"},
+		processor.AssetsShared:    {"http://example.com/a", "http://example.com/b", "http://example.com/logo.png"},
+	}
+	for _, mode := range processor.AssetModes {
+		var out bytes.Buffer
+		fanOut := processor.NewFanOut(map[string]wire.GraphProcessor{"json": processor.NewJSON(&out)}, nil,
+			processor.AssetOptions{Mode: mode, MinPages: 1})
+		pages := make(chan *wire.Page, 2)
+		fanOut.ProcessLoop(pages)
+		pages <- assetPage(t, "http://example.com/a", "main.css", "logo.png")
+		pages <- assetPage(t, "http://example.com/b", "logo.png", "app.js")
+		close(pages)
+		if errs := fanOut.Finish(&wire.CrawlInfo{}); len(errs) != 0 {
+			t.Fatalf("Unexpected errors %v", errs)
+		}
+
+		var doc processor.JSONDocument
+		if err := json.Unmarshal(out.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		var nodes []string
+		for _, node := range doc.Nodes {
+			nodes = append(nodes, node.URL)
+		}
+		if strings.Join(nodes, " ") != strings.Join(expected[mode], " ") {
+			t.Errorf("Expected nodes %v with assets %s, got %v", expected[mode], mode, nodes)
+		}
+		if mode == processor.AssetsAggregate && doc.Nodes[1].Title != "2 assets: css 1, png 1" {
+			t.Errorf("Unexpected title of aggregated assets %q", doc.Nodes[1].Title)
+		}
+	}
+
+	if err := (processor.AssetOptions{Mode: "some"}).Validate(); err == nil {
+		t.Errorf("Expected unknown asset mode to fail")
+	}
+}
+
+func TestDotLabels(t *testing.T) {
+	var out bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"dot": processor.NewDotPrinter(&out, processor.DotOptions{Label: "title", Tooltip: "description"}),
+	})
+	if !strings.Contains(out.String(), `label="First post"`) || !strings.Contains(out.String(), `tooltip="The home page"`) {
+		t.Fatalf("Expected titles as labels and descriptions as tooltips:\n%s", out.String())
+	}
+	if err := (processor.DotOptions{Label: "body"}).Validate(); err == nil {
+		t.Errorf("Expected unknown page field to fail")
+	}
+}
+
+func TestRank(t *testing.T) {
+	nodes := []processor.Node{
+		{URL: "a", Type: processor.NodePage},
+		{URL: "b", Type: processor.NodePage},
+		{URL: "c", Type: processor.NodePage},
+		{URL: "c.css", Type: processor.NodeAsset},
+	}
+	edges := []processor.Edge{
+		{Source: "a", Target: "b", Card: 3, Kind: processor.EdgeLink},
+		{Source: "a", Target: "c", Card: 1, Kind: processor.EdgeLink},
+		{Source: "a", Target: "a", Card: 5, Kind: processor.EdgeLink},
+		{Source: "b", Target: "a", Card: 1, Kind: processor.EdgeLink},
+		{Source: "c", Target: "a", Card: 1, Kind: processor.EdgeLink},
+		{Source: "c", Target: "c.css", Card: 1, Kind: processor.EdgeAsset},
+	}
+	ranks := processor.Rank(nodes, edges)
+	if len(ranks) != 3 || ranks[0].URL != "a" || ranks[1].URL != "b" || ranks[2].URL != "c" {
+		t.Fatalf("Expected pages ranked a, b, c: %+v", ranks)
+	}
+	sum := ranks[0].Rank + ranks[1].Rank + ranks[2].Rank
+	if sum < 0.999 || sum > 1.001 {
+		t.Errorf("Ranks sum up to %f", sum)
+	}
+	if ranks[0].InDegree != 2 || ranks[0].OutDegree != 2 || ranks[0].Betweenness != 1 || ranks[1].Betweenness != 0 {
+		t.Errorf("Bad degrees or betweenness: %+v", ranks)
+	}
+}
+
+func TestRankOutputs(t *testing.T) {
+	var report, dot bytes.Buffer
+	crawlTestSite(t, map[string]wire.GraphProcessor{
+		"rank": processor.NewRankReport(&report),
+		"dot":  processor.NewDotPrinter(&dot, processor.DotOptions{Rank: true}),
+	})
+
+	rows, err := csv.NewReader(&report).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 5 || rows[1][0] != "1" || !strings.HasSuffix(rows[1][1], "/") {
+		t.Fatalf("Expected 4 pages with the root first: %v", rows)
+	}
+	if !strings.Contains(dot.String(), "colorscheme=ylorrd9") || !strings.Contains(dot.String(), "fillcolor=9") {
+		t.Fatalf("Pages not colored by rank:\n%s", dot.String())
+	}
+}
+
+func TestDepthReport(t *testing.T) {
+	links := map[string][]string{
+		"/":  {"/a", "/b"},
+		"/a": {"/"},
+		"/b": {"/c"},
+		"/c": {"/d"},
+	}
+	var report bytes.Buffer
+	fanOut := processor.NewFanOut(map[string]wire.GraphProcessor{
+		"depth": processor.NewDepthReport(&report, processor.DepthOptions{
+			MaxDepth: 2,
+			Known:    []string{"http://example.com/c", "http://example.com/orphan"},
+		}),
+	}, nil, processor.AssetOptions{})
+	pages := make(chan *wire.Page, len(links))
+	fanOut.ProcessLoop(pages)
+	for _, path := range []string{"/", "/a", "/b", "/c"} {
+		iPage := assetPage(t, "http://example.com"+path)
+		iPage.OutLinks = make(map[string]*wire.PageWithCard)
+		for _, link := range links[path] {
+			linked := assetPage(t, "http://example.com"+link)
+			iPage.OutLinks[linked.PageURL.String()] = &wire.PageWithCard{Page: linked, Card: 1}
+		}
+		pages <- iPage
+	}
+	close(pages)
+	fanOut.Finish(&wire.CrawlInfo{RootURL: "http://example.com/"})
+
+	expected := `Click depth from http://example.com/
+depth   pages
+0       1
+1       2
+2       1
+3       1
+
+Pages deeper than 2 clicks (1)
+3       http://example.com/d
+
+Orphan pages, of 2 known (1)
+http://example.com/orphan
+`
+	if report.String() != expected {
+		t.Fatalf("Expected report:\n%s\ngot:\n%s", expected, report.String())
+	}
+}
+
+func TestParseSitemap(t *testing.T) {
+	files := make(memFiles)
+	crawlTestSite(t, map[string]wire.GraphProcessor{"sitemap": processor.NewSitemap(files.create, 3, false)})
+
+	_, sitemaps, err := processor.ParseSitemap(files["sitemap.xml"])
+	if err != nil || len(sitemaps) != 2 {
+		t.Fatalf("Expected 2 sitemaps in the index, got %v %v", sitemaps, err)
+	}
+	urls, _, err := processor.ParseSitemap(files["sitemap-1.xml"])
+	if err != nil || len(urls) != 3 {
+		t.Fatalf("Expected 3 urls in the sitemap, got %v %v", urls, err)
+	}
+}
+
+func TestLoadGraph(t *testing.T) {
+	var jsonOut, dotOut bytes.Buffer
+	result := crawlTestSite(t, map[string]wire.GraphProcessor{
+		"json": processor.NewJSON(&jsonOut),
+		"dot":  processor.NewPrinter(&dotOut),
+	})
+
+	for name, out := range map[string]*bytes.Buffer{"json": &jsonOut, "dot": &dotOut} {
+		doc, err := processor.LoadGraph(out)
+		if err != nil {
+			t.Fatalf("Failed to load %s: %s", name, err)
+		}
+		if len(doc.Nodes) != 7 || len(doc.Edges) != 10 {
+			t.Fatalf("Expected 7 nodes and 10 edges from %s, got %d %d", name, len(doc.Nodes), len(doc.Edges))
+		}
+		pages, err := doc.Pages()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pages) != len(testSite) || pages[0].PageURL.String() != result.RootURL {
+			t.Fatalf("Expected %d pages from %s, root first, got %v", len(testSite), name, pages)
+		}
+		if home := pages[0]; home.OutLinks[result.RootURL+"about"].Card != 2 || len(home.StatList) != 2 {
+			t.Fatalf("Bad links of home page from %s: %+v", name, home)
+		}
+	}
+}
+
+func TestShortestPaths(t *testing.T) {
+	var out bytes.Buffer
+	result := crawlTestSite(t, map[string]wire.GraphProcessor{"json": processor.NewJSON(&out)})
+	doc, err := processor.LoadGraph(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := result.RootURL
+	paths := processor.ShortestPaths(doc.Edges, root, root+"blog/first", 0)
+	if len(paths) != 1 || strings.Join(paths[0], " ") != root+" "+root+"blog "+root+"blog/first" {
+		t.Fatalf("Bad shortest path: %v", paths)
+	}
+	if paths := processor.ShortestPaths(doc.Edges, root, root+"logo.png", 0); paths != nil {
+		t.Fatalf("Expected no path to an asset, got %v", paths)
+	}
+
+	sub := doc.Subgraph(paths)
+	if len(sub.Nodes) != 3 || len(sub.Edges) != 2 {
+		t.Fatalf("Expected 3 nodes and 2 edges in the subgraph, got %+v", sub)
+	}
+
+	// /b is walked first, the path over /a still comes first.
+	edges := []processor.Edge{
+		{Source: "/", Target: "/b", Kind: processor.EdgeLink},
+		{Source: "/", Target: "/a", Kind: processor.EdgeLink},
+		{Source: "/b", Target: "/c", Kind: processor.EdgeLink},
+		{Source: "/a", Target: "/c", Kind: processor.EdgeLink},
+	}
+	if paths := processor.ShortestPaths(edges, "/", "/c", 1); len(paths) != 1 || strings.Join(paths[0], " ") != "/ /a /c" {
+		t.Fatalf("Expected the first shortest path in sort order, got %v", paths)
+	}
+	if paths := processor.ShortestPaths(edges, "/", "/c", 0); len(paths) != 2 {
+		t.Fatalf("Expected 2 shortest paths, got %v", paths)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	page := func(link string, status int) processor.Node {
+		return processor.Node{URL: "http://example.com" + link, Status: status, Type: processor.NodePage}
+	}
+	edge := func(source, target, kind string) processor.Edge {
+		return processor.Edge{Source: "http://example.com" + source, Target: "http://example.com" + target, Card: 1, Kind: kind}
+	}
+	oldDoc := &processor.JSONDocument{
+		Nodes: []processor.Node{page("/", 200), page("/a", 200), page("/b", 200),
+			{URL: "http://example.com/old.css", Type: processor.NodeAsset}},
+		Edges: []processor.Edge{edge("/", "/a", processor.EdgeLink), edge("/", "/b", processor.EdgeLink),
+			edge("/", "/old.css", processor.EdgeAsset)},
+	}
+	newDoc := &processor.JSONDocument{
+		Nodes: []processor.Node{page("/", 200), page("/a", 404), page("/c", 200),
+			{URL: "http://example.com/new.css", Type: processor.NodeAsset}},
+		Edges: []processor.Edge{edge("/", "/a", processor.EdgeLink), edge("/", "/c", processor.EdgeLink),
+			edge("/", "/new.css", processor.EdgeAsset)},
+	}
+
+	diff := processor.Diff(oldDoc, newDoc)
+	var report bytes.Buffer
+	if err := diff.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Pages (2)
++ http://example.com/c
+- http://example.com/b
+Status (1)
+  http://example.com/a 200 -> 404
+Links (2)
++ http://example.com/ -> http://example.com/c
+- http://example.com/ -> http://example.com/b
+Assets (2)
++ http://example.com/ -> http://example.com/new.css
+- http://example.com/ -> http://example.com/old.css
+`
+	if report.String() != expected {
+		t.Fatalf("Expected report:\n%s\ngot:\n%s", expected, report.String())
+	}
+	if !processor.Diff(newDoc, newDoc).Empty() || diff.Empty() {
+		t.Fatalf("Bad Empty of diffs")
+	}
+
+	var graph bytes.Buffer
+	if err := diff.WriteDot(&graph, oldDoc, newDoc); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		`"http://example.com/c" [ URL="http://example.com/c", color=green`,
+		`"http://example.com/b" [ URL="http://example.com/b", color=red`,
+		`"http://example.com/a" [ URL="http://example.com/a", color=orange`,
+	} {
+		if !strings.Contains(graph.String(), expected) {
+			t.Fatalf("Expected %s in graph:\n%s", expected, graph.String())
+		}
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	var words []string
+	for i := 0; i < 2000; i++ {
+		words = append(words, "word"+strconv.Itoa(i%700))
+	}
+	text := strings.Join(words, " ")
+	edited := strings.Replace(text, "word42 word43", "word42 edited", 1)
+	pages := map[string]string{
+		"/":  `<html><body><a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a> <a href="/d">D</a> <a href="/gone">Gone</a> <a href="/lost">Lost</a></body></html>`,
+		"/a": `<html><body><p>` + text + `</p></body></html>`,
+		"/b": `<html><body><p>` + text + `</p></body></html>`,
+		"/c": `<html><body><p>` + edited + `</p></body></html>`,
+		"/d": `<html><body><p>Something else entirely, not a duplicate of anything.</p></body></html>`,
+	}
+	var report, graph bytes.Buffer
+	result := crawlSite(t, pages, map[string]wire.GraphProcessor{
+		"duplicates": processor.NewDuplicateReport(&report),
+		"dot":        processor.NewDotPrinter(&graph, processor.DotOptions{Duplicates: true}),
+	})
+
+	root := result.RootURL
+	expected := `Exact duplicates (1)
+1   ` + root + `a
+1   ` + root + `b
+
+Near duplicates, up to 3 bits apart (1)
+2   ` + root + `a
+2   ` + root + `b
+2   ` + root + `c
+`
+	if report.String() != expected {
+		t.Fatalf("Expected report:\n%s\ngot:\n%s", expected, report.String())
+	}
+	if !strings.Contains(graph.String(), `c", color=blue, penwidth=3 ]`) {
+		t.Fatalf("Expected near duplicates outlined in the graph:\n%s", graph.String())
+	}
+}
+
+func TestAudit(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><head><title>Home</title><meta name="description" content="Same"><link rel="canonical" href="/"></head>
+<body><h1>Home</h1><a href="/a">A</a> <a href="/b">B</a> <a href="/c">C</a><img src="/logo.png"><img src="/spacer.png" alt=""></body></html>`,
+		"/a": `<html><head><title>Home</title><meta name="description" content="Same"><meta name="ROBOTS" content="noindex, follow"></head>
+<body><h1>One</h1><h1>Two</h1></body></html>`,
+		"/b": `<html><head><link rel="Canonical" href="/b"></head><body>Nothing</body></html>`,
+		"/c": `<html><head><title>` + strings.Repeat("long ", 15) + `</title><meta name="description" content="C"><link rel="canonical" href="/c"></head>
+<body><h1>C</h1></body></html>`,
+	}
+	var auditJSON, auditCSV bytes.Buffer
+	result := crawlSite(t, pages, map[string]wire.GraphProcessor{
+		"json": processor.NewAuditJSON(&auditJSON, processor.AuditOptions{}),
+		"csv": processor.NewAuditCSV(&auditCSV, processor.AuditOptions{
+			Rules: []string{processor.RuleMissingTitle, processor.RuleLinkedNoIndex},
+			Skip:  []string{processor.RuleLinkedNoIndex},
+		}),
+	})
+
+	var doc processor.AuditDocument
+	if err := json.Unmarshal(auditJSON.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Version != processor.AuditVersion || len(doc.Results) != len(processor.AuditRules) {
+		t.Fatalf("Expected a result per rule, got %+v", doc)
+	}
+	root := result.RootURL
+	expected := map[string]string{
+		processor.RuleMissingTitle:         "b",
+		processor.RuleDuplicateTitle:       " a",
+		processor.RuleLongTitle:            "c",
+		processor.RuleMissingDescription:   "b",
+		processor.RuleDuplicateDescription: " a",
+		processor.RuleMissingH1:            "b",
+		processor.RuleMultipleH1:           "a",
+		processor.RuleImageAlt:             "",
+		processor.RuleMissingCanonical:     "a",
+		processor.RuleLinkedNoIndex:        "a",
+	}
+	for _, audit := range doc.Results {
+		var paths []string
+		for _, issue := range audit.Issues {
+			paths = append(paths, strings.TrimPrefix(issue.URL, root))
+		}
+		if strings.Join(paths, " ") != expected[audit.Rule] {
+			t.Fatalf("Expected %q for %s, got %+v", expected[audit.Rule], audit.Rule, audit.Issues)
+		}
+		if audit.Rule == processor.RuleImageAlt && (len(audit.Issues) != 1 || audit.Issues[0].Detail != "1") {
+			t.Fatalf("Expected the image without alt of the root, got %+v", audit.Issues)
+		}
+	}
+
+	expectedCSV := "rule,url,detail\nmissing-title," + root + "b,\n"
+	if auditCSV.String() != expectedCSV {
+		t.Fatalf("Expected csv:\n%s\ngot:\n%s", expectedCSV, auditCSV.String())
+	}
+	if err := (processor.AuditOptions{Skip: []string{"no-such-rule"}}).Validate(); err == nil {
+		t.Fatal("Expected unknown rules to fail validation")
+	}
+}
+
+func TestBrokenFragments(t *testing.T) {
+	pages := map[string]string{
+		"/": `<html><body><h2 id="local">Here</h2>
+<a href="/guide#install">Install</a> <a href="/guide#missing">Missing</a> <a href="/guide#missing">Again</a>
+<a href="/guide#intro">Intro</a> <a href="/guide#top">Top</a> <a href="#local">Local</a> <a href="#nowhere">Nowhere</a></body></html>`,
+		"/guide": `<html><body><a name="intro"></a><h2 id="install">Install</h2><div name="other"></div>
+<a href="/#other">Other</a></body></html>`,
+	}
+	var report bytes.Buffer
+	result := crawlSite(t, pages, map[string]wire.GraphProcessor{
+		"fragments": processor.NewFragmentReport(&report),
+	})
+
+	root := result.RootURL
+	expected := `Broken fragment links (3)
+1   ` + root + `#nowhere on ` + root + `
+2   ` + root + `guide#missing on ` + root + `
+1   ` + root + `#other on ` + root + `guide
+`
+	if report.String() != expected {
+		t.Fatalf("Expected report:\n%s\ngot:\n%s", expected, report.String())
+	}
+}
+
+func TestMixedContent(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	pages := map[string]string{
+		"/": `<html><head><link rel="stylesheet" href="http://cdn.example.com/main.css">
+<link rel="canonical" href="http://example.com/"><link rel="icon" href="http://cdn.example.com/favicon.ico">
+<script src="http://cdn.example.com/app.js"></script></head>
+<body><img src="http://cdn.example.com/logo.png"><img src="/safe.png"><iframe src="http://example.com/widget"></iframe>
+<a href="/about">About</a> <a href="http://example.com/elsewhere">Elsewhere</a></body></html>`,
+	}
+	site := httptest.NewTLSServer(siteHandler(pages))
+	defer site.Close()
+	insecureURL := "http://" + strings.TrimPrefix(site.URL, "https://") + "/"
+	pages["/about"] = `<html><body><video src="http://cdn.example.com/intro.mp4"></video><a href="` + insecureURL + `">Home</a></body></html>`
+
+	var report, graph bytes.Buffer
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.Transport = site.Client().Transport
+	config.Graph.Insecure = true
+	config.Processors = map[string]wire.GraphProcessor{"mixed": processor.NewMixedContentReport(&report)}
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph.WriteString(result.Graph)
+
+	// The test server is not example.com, so the link
+	// to http://example.com/elsewhere is not a downgrade.
+	root := result.RootURL
+	expected := `Pages with insecure references (2)
+
+Active mixed content (3)
+script  http://cdn.example.com/app.js on ` + root + `
+link    http://cdn.example.com/main.css on ` + root + `
+iframe  http://example.com/widget on ` + root + `
+
+Passive mixed content (3)
+link    http://cdn.example.com/favicon.ico on ` + root + `
+img     http://cdn.example.com/logo.png on ` + root + `
+video   http://cdn.example.com/intro.mp4 on ` + root + `about
+
+Links downgrading to http (1)
+a       ` + insecureURL + ` on ` + root + `about
+`
+	if report.String() != expected {
+		t.Fatalf("Expected report:\n%s\ngot:\n%s", expected, report.String())
+	}
+	if !strings.Contains(graph.String(), `fontcolor=red`) || !strings.Contains(graph.String(), `fontcolor=orange`) {
+		t.Fatalf("Expected insecure pages colored in the graph:\n%s", graph.String())
+	}
+}
+
+func TestHeaderAudit(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	pages := map[string]string{
+		"/":      `<html><body><a href="/about">About</a> <a href="/login">Login</a></body></html>`,
+		"/about": `<html><body><a href="/">Home</a></body></html>`,
+		"/login": `<html><body><a href="/">Home</a></body></html>`,
+	}
+	handler := siteHandler(pages)
+	site := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		switch r.URL.Path {
+		case "/":
+			header.Set("Strict-Transport-Security", "max-age=31536000")
+			header.Set("X-Frame-Options", "SAMEORIGIN")
+			header.Set("Referrer-Policy", "no-referrer")
+		case "/about":
+			header.Set("Content-Security-Policy", "frame-ancestors 'none'")
+			header.Set("Strict-Transport-Security", "max-age=0")
+			header.Set("Referrer-Policy", "unsafe-url")
+			header.Add("Set-Cookie", "session=1; Secure; HttpOnly; SameSite=Strict")
+		case "/login":
+			header.Set("X-Frame-Options", "SAMEORIGIN")
+			header.Add("Set-Cookie", "session=1; Secure")
+			header.Add("Set-Cookie", "csrf=1; Secure; HttpOnly; SameSite=Lax")
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer site.Close()
+
+	var matrix, summary bytes.Buffer
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.Transport = site.Client().Transport
+	config.Processors = map[string]wire.GraphProcessor{"headers": processor.NewHeaderAudit(&matrix, &summary)}
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ProcessorErrors) != 0 {
+		t.Fatalf("Header audit failed: %v", result.ProcessorErrors)
+	}
+
+	root := result.RootURL
+	expected := `url,status,csp,hsts,nosniff,framing,referrer,cookies
+` + root + `,200,missing,ok,ok,ok,ok,n/a
+` + root + `about,200,ok,max-age=0,ok,ok,unsafe-url,ok
+` + root + `login,200,missing,missing,ok,ok,missing,"session: no HttpOnly, no SameSite"
+`
+	if matrix.String() != expected {
+		t.Fatalf("Expected matrix:\n%s\ngot:\n%s", expected, matrix.String())
+	}
+	expected = `Security headers of 3 pages
+
+Inconsistent across pages (5)
+Content-Security-Policy
+2       (missing)
+1       frame-ancestors 'none'
+Strict-Transport-Security
+1       (missing)
+1       max-age=0
+1       max-age=31536000
+X-Frame-Options
+2       SAMEORIGIN
+1       (missing)
+Referrer-Policy
+1       (missing)
+1       no-referrer
+1       unsafe-url
+Set-Cookie session
+1       Secure
+1       Secure HttpOnly SameSite=strict
+
+Missing on every page (0)
+
+Same on every page (2)
+X-Content-Type-Options: nosniff
+Set-Cookie csrf: Secure HttpOnly SameSite=lax
+
+Cookies not set on every page (2)
+Set-Cookie csrf: set on 1 of 3 pages
+Set-Cookie session: set on 2 of 3 pages
+`
+	if summary.String() != expected {
+		t.Fatalf("Expected summary:\n%s\ngot:\n%s", expected, summary.String())
+	}
+}
+
+func TestExternalLinks(t *testing.T) {
+	flag.Lookup("alsologtostderr").Value.Set("false")
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/nohead" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer external.Close()
+	otherHost := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+	refused := "http://127.0.0.1:1/"
+	pages := map[string]string{
+		"/": `<html><body><a href="` + external.URL + `/ok">Ok</a> <a href="` + external.URL + `/gone">Gone</a>
+<a href="` + external.URL + `/gone#top">Gone</a> <a href="` + external.URL + `/nohead">No HEAD</a> <a href="/about">About</a></body></html>`,
+		"/about": `<html><body><a href="` + external.URL + `/gone">Gone</a> <a href="` + otherHost + `/ok">Other</a>
+<a href="` + refused + `">Refused</a> <a href="mailto:me@example.com">Mail</a></body></html>`,
+	}
+	site := newSite(pages)
+	defer site.Close()
+
+	var report, saved bytes.Buffer
+	config := dotler.DefaultConfig()
+	config.RootURL = site.URL + "/"
+	config.External.Check = true
+	config.Processors = map[string]wire.GraphProcessor{
+		"external": processor.NewExternalReport(&report),
+		"json":     processor.NewJSON(&saved),
+	}
+	crawler, err := dotler.NewCrawler(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := crawler.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ProcessorErrors) != 0 {
+		t.Fatalf("External report failed: %v", result.ProcessorErrors)
+	}
+
+	host := strings.TrimPrefix(external.URL, "http://")
+	if len(result.External) != 5 || result.External[external.URL+"/ok"].Status != 200 ||
+		result.External[external.URL+"/gone"].Status != 404 || result.External[external.URL+"/nohead"].Status != 200 ||
+		result.External[refused].Error == "" {
+		t.Fatalf("Wrong checks of external links: %+v", result.External)
+	}
+
+	root := result.RootURL
+	expected := `External hosts (3)
+5       ` + host + `
+1       127.0.0.1:1
+1       ` + strings.TrimPrefix(otherHost, "http://") + `
+
+Broken external links (2)
+error   ` + refused + ` on ` + root + `about: ` + result.External[refused].Error + `
+404     ` + external.URL + `/gone on ` + root + `
+404     ` + external.URL + `/gone on ` + root + `about
+`
+	if report.String() != expected {
+		t.Fatalf("Expected report:\n%s\ngot:\n%s", expected, report.String())
+	}
+
+	if err = (dotler.ExternalOptions{Check: true, Concurrency: 1, Rate: 2e9}).Validate(); err == nil {
+		t.Fatalf("Expected a check rate above one a nanosecond to be rejected")
+	}
+
+	if !strings.Contains(result.Graph, "shape=box") || !strings.Contains(result.Graph, "fontcolor=red") {
+		t.Fatalf("Expected hosts of external links in the graph:\n%s", result.Graph)
+	}
+	graphDoc, err := processor.LoadGraph(strings.NewReader(result.Graph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graphDoc.Nodes) != 2 || len(graphDoc.Edges) != 1 {
+		t.Fatalf("Hosts of external links loaded as pages: %+v", graphDoc)
+	}
+
+	doc, err := processor.LoadGraph(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks := doc.LinkChecks(); len(checks) != len(result.External) || checks[external.URL+"/gone"] != result.External[external.URL+"/gone"] {
+		t.Fatalf("Checks of external links not saved: %+v", doc.ExternalChecks)
+	}
+	if len(doc.Nodes[0].External) != 3 || doc.Nodes[0].External[0].Card != 2 {
+		t.Fatalf("External links of %s not saved: %+v", doc.Nodes[0].URL, doc.Nodes[0].External)
+	}
+}
@@ -0,0 +1,43 @@
+package dotler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+)
+
+// A small site served locally, so that crawls can be tested offline.
+var testSite = map[string]string{
+	"/": `<html lang="en"><head><title>Home</title><link rel="stylesheet" href="/main.css">
+<meta name="Description" content="The home page"></head>
+<body><h1>Welcome  home</h1><script>var notWords = 1;</script><nav><a href="/about" title="About this site">About</a></nav> <a href="/blog" rel="Next">Blog</a> <a href="/about">About us</a>
+<img src="/logo.png"></body></html>`,
+	"/about": `<html><head><title>About</title></head>
+<body><a href="/">Home</a><script src="/app.js"></script></body></html>`,
+	"/blog": `<html><head><title>Blog</title></head>
+<body><a href="/blog/first">First</a> <a href="/">Home</a></body></html>`,
+	"/blog/first": `<html><head><title>First post</title></head>
+<body><a href="/blog">Blog</a><img src="/logo.png"></body></html>`,
+}
+
+func newTestSite() *httptest.Server {
+	return newSite(testSite)
+}
+
+// Serves pages, keyed by path, locally.
+func newSite(pages map[string]string) *httptest.Server {
+	return httptest.NewServer(siteHandler(pages))
+}
+
+// Handler serving pages, keyed by path.
+func siteHandler(pages map[string]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, exists := pages[r.URL.Path]
+		if !exists {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2017 15:04:05 GMT")
+		w.Write([]byte(body))
+	})
+}
@@ -0,0 +1,265 @@
+// Copyright 2017 Raghavendra Prabhu.
+// Refer to LICENSE for more
+
+package wire
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+var pageBucket = []byte("pages")
+
+// spilledPage is the on-disk form of a Page.
+// Links are stored as keys only, the pages they point to
+// are spilled (or kept in memory) on their own.
+type spilledPage struct {
+	PageURL      string
+	OutLinks     map[string]uint
+	Links        map[string][]Link
+	External     map[string]uint
+	StatList     map[string]string
+	FailCount    uint
+	Title        string
+	Description  string
+	H1           string
+	H1Count      int
+	MissingAlt   int
+	Canonical    string
+	NoIndex      bool
+	Anchors      []string
+	Insecure     []InsecureRef
+	Headers      map[string]string
+	Cookies      []Cookie
+	Lang         string
+	WordCount    int
+	ContentHash  string
+	SimHash      uint64
+	Status       int
+	Depth        uint
+	LastModified time.Time
+}
+
+// BoltMap is a NodeMapper backed by an embedded bbolt store.
+// Pages live in memory while being crawled and are moved to disk
+// with Spill once the graph processors are done with them.
+type BoltMap struct {
+	sync.RWMutex
+	hot map[string]*Page
+	// Urls of the pages on disk, so that looking them up needs no read.
+	spilled map[string]*url.URL
+	db      *bolt.DB
+}
+
+// NewBoltMapper returns a NodeMapper which spills pages to the bbolt
+// database at path. Any existing database at path is truncated.
+// The returned CancelFunc closes the database.
+func NewBoltMapper(path string) (NodeMapper, context.CancelFunc, error) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return nil, nil, err
+	}
+	// The store only lives as long as the crawl, no need to fsync.
+	db, err := bolt.Open(path, 0644, &bolt.Options{NoSync: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(pageBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, nil, err
+	}
+
+	node := &BoltMap{hot: make(map[string]*Page), spilled: make(map[string]*url.URL), db: db}
+	var once sync.Once
+	return node, func() { once.Do(func() { db.Close() }) }, nil
+}
+
+func (node *BoltMap) onDisk(skey string) []byte {
+	var value []byte
+	node.db.View(func(tx *bolt.Tx) error {
+		if stored := tx.Bucket(pageBucket).Get([]byte(skey)); stored != nil {
+			value = append([]byte(nil), stored...)
+		}
+		return nil
+	})
+	return value
+}
+
+// Add method allows one to add new keys.
+// Returns error if the key is either in memory or on disk.
+func (node *BoltMap) Add(key string, value *Page) error {
+	skey := httpStrip(key)
+	node.Lock()
+	defer node.Unlock()
+	if _, exists := node.hot[skey]; exists || node.spilled[skey] != nil {
+		return fmt.Errorf("Key %s already existed", key)
+	}
+	node.hot[skey] = value
+	return nil
+}
+
+// Exists method allows to check and return the key.
+// For a page already spilled to disk, a Page with only
+// its PageURL is returned, see Load for the rest.
+func (node *BoltMap) Exists(key string) *Page {
+	skey := httpStrip(key)
+	node.RLock()
+	defer node.RUnlock()
+	if page, exists := node.hot[skey]; exists {
+		return page
+	}
+	if pageURL := node.spilled[skey]; pageURL != nil {
+		return &Page{PageURL: pageURL}
+	}
+	return nil
+}
+
+// Spill writes the page at key to disk and releases
+// its links, assets, headers and the like from memory.
+func (node *BoltMap) Spill(key string) error {
+	skey := httpStrip(key)
+	node.Lock()
+	defer node.Unlock()
+	page, exists := node.hot[skey]
+	if !exists {
+		return fmt.Errorf("Key %s is not in memory", key)
+	}
+	encoded, err := encodePage(page)
+	if err != nil {
+		return err
+	}
+	err = node.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(pageBucket).Put([]byte(skey), encoded)
+	})
+	if err != nil {
+		return err
+	}
+	delete(node.hot, skey)
+	node.spilled[skey] = page.PageURL
+	page.OutLinks = nil
+	page.External = nil
+	page.StatList = nil
+	page.Anchors = nil
+	page.Insecure = nil
+	page.Headers = nil
+	page.Cookies = nil
+	return nil
+}
+
+// Load returns a full copy of the page at key from memory or disk.
+// Links of a loaded page point to Pages with only PageURL set.
+func (node *BoltMap) Load(key string) (*Page, error) {
+	skey := httpStrip(key)
+	node.RLock()
+	defer node.RUnlock()
+	if page, exists := node.hot[skey]; exists {
+		return page, nil
+	}
+	stored := node.onDisk(skey)
+	if stored == nil {
+		return nil, fmt.Errorf("Key %s does not exist", key)
+	}
+	return decodePage(stored)
+}
+
+func encodePage(page *Page) ([]byte, error) {
+	sPage := spilledPage{
+		PageURL:      page.PageURL.String(),
+		FailCount:    page.FailCount,
+		Title:        page.Title,
+		Description:  page.Description,
+		H1:           page.H1,
+		H1Count:      page.H1Count,
+		MissingAlt:   page.MissingAlt,
+		Canonical:    page.Canonical,
+		NoIndex:      page.NoIndex,
+		Anchors:      page.Anchors,
+		Insecure:     page.Insecure,
+		Headers:      page.Headers,
+		Cookies:      page.Cookies,
+		Lang:         page.Lang,
+		WordCount:    page.WordCount,
+		ContentHash:  page.ContentHash,
+		SimHash:      page.SimHash,
+		Status:       page.Status,
+		Depth:        page.Depth,
+		LastModified: page.LastModified,
+		OutLinks:     make(map[string]uint, len(page.OutLinks)),
+		Links:        make(map[string][]Link, len(page.OutLinks)),
+		External:     page.External,
+		StatList:     make(map[string]string, len(page.StatList)),
+	}
+	for link, oPage := range page.OutLinks {
+		sPage.OutLinks[link] = oPage.Card
+		sPage.Links[link] = oPage.Links
+	}
+	for link, sLink := range page.StatList {
+		sPage.StatList[link] = sLink.PageTitle
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&sPage); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodePage(stored []byte) (*Page, error) {
+	var sPage spilledPage
+	if err := gob.NewDecoder(bytes.NewReader(stored)).Decode(&sPage); err != nil {
+		return nil, err
+	}
+	pageURL, err := url.Parse(sPage.PageURL)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{
+		PageURL:      pageURL,
+		FailCount:    sPage.FailCount,
+		Title:        sPage.Title,
+		Description:  sPage.Description,
+		H1:           sPage.H1,
+		H1Count:      sPage.H1Count,
+		MissingAlt:   sPage.MissingAlt,
+		Canonical:    sPage.Canonical,
+		NoIndex:      sPage.NoIndex,
+		Anchors:      sPage.Anchors,
+		Insecure:     sPage.Insecure,
+		Headers:      sPage.Headers,
+		Cookies:      sPage.Cookies,
+		Lang:         sPage.Lang,
+		WordCount:    sPage.WordCount,
+		ContentHash:  sPage.ContentHash,
+		SimHash:      sPage.SimHash,
+		Status:       sPage.Status,
+		Depth:        sPage.Depth,
+		LastModified: sPage.LastModified,
+		OutLinks:     make(map[string]*PageWithCard, len(sPage.OutLinks)),
+		External:     sPage.External,
+		StatList:     make(map[string]StatPage, len(sPage.StatList)),
+	}
+	for link, card := range sPage.OutLinks {
+		linkURL, err := url.Parse(link)
+		if err != nil {
+			return nil, err
+		}
+		page.OutLinks[link] = &PageWithCard{Page: &Page{PageURL: linkURL}, Card: card, Links: sPage.Links[link]}
+	}
+	for link, title := range sPage.StatList {
+		linkURL, err := url.Parse(link)
+		if err != nil {
+			return nil, err
+		}
+		page.StatList[link] = StatPage{PageTitle: title, StaticURL: linkURL}
+	}
+	return page, nil
+}
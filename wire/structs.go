@@ -5,9 +5,9 @@
 package wire
 
 import (
-	"context"
 	gmap "github.com/ronin13/goimutmap"
 	"net/url"
+	"time"
 )
 
 // StatPage maintains
@@ -18,24 +18,145 @@ type StatPage struct {
 	StaticURL *url.URL
 }
 
+// Link is a single occurrence of a link to a page:
+// - text: anchor text, alt of the image for image links
+// - title: title attribute, if any
+// - rel: values of the rel attribute, lower cased
+// - region: nearest landmark, nav, header, footer, main or aside, if any
+// - fragment: the #fragment of the link, if any, without the #
+type Link struct {
+	Text     string
+	Title    string
+	Rel      []string
+	Region   string
+	Fragment string
+}
+
 // PageWithCard is a struct which encapsulates a Page with its cardinality.
 // A page can have multiple links to another single page
 // card here is cardinality - number of links to that page.
+// Links has every such link, in order of the document.
 type PageWithCard struct {
-	Page *Page
-	Card uint
+	Page  *Page
+	Card  uint
+	Links []Link
+}
+
+// Anchor returns the most common anchor text of the links,
+// the first in sort order on ties, empty if none has any.
+func (oPage *PageWithCard) Anchor() string {
+	var anchor string
+	counts := make(map[string]int)
+	for _, link := range oPage.Links {
+		if link.Text == "" {
+			continue
+		}
+		counts[link.Text]++
+		if count := counts[link.Text]; count > counts[anchor] || (count == counts[anchor] && link.Text < anchor) {
+			anchor = link.Text
+		}
+	}
+	return anchor
+}
+
+// InsecureRef is a http:// reference of a https page:
+// - url: the reference
+// - element: the tag it is in, script, img, a etc.
+// - severity: active, passive or downgrade, see processor.Severities
+type InsecureRef struct {
+	URL      string
+	Element  string
+	Severity string
+}
+
+// Cookie is how a cookie set by a page is flagged:
+// - name: name of the cookie
+// - secure, httpOnly: if the Secure and HttpOnly flags are set
+// - sameSite: value of SameSite, lax, strict or none, if any
+type Cookie struct {
+	Name     string
+	Secure   bool
+	HTTPOnly bool
+	SameSite string
+}
+
+// LinkCheck is the result of checking a link to another host:
+// - status: HTTP status code of the response, 0 if the request failed
+// - error: why the request failed, if it did
+type LinkCheck struct {
+	Status int
+	Error  string
 }
 
 // Page maintains:
 // - statList: a map of URL to StatPage
 // - outLinks: a map of URL to Page
+// - external: a map of URL on other hosts to the number of links to it
 // - pageURL:  URL structure
 // - failCount: number of times this page is tried
+// - title: contents of <title>, once crawled
+// - description: content of <meta name="description">, if any
+// - h1: text of the first <h1>, if any
+// - h1Count: number of <h1>
+// - missingAlt: number of <img> without an alt attribute
+// - canonical: absolute url of <link rel="canonical">, if any
+// - noIndex: if robots <meta> or X-Robots-Tag have noindex
+// - anchors: sorted ids of elements and names of <a>, fragments may point to
+// - insecure: http:// references of a https page
+// - headers: security headers of the response, see processor.SecurityHeaders
+// - cookies: cookies set by the response
+// - lang: lang attribute of <html>, if any
+// - wordCount: number of words in the text of <body>
+// - contentHash: hex SHA-256 of the body, once crawled
+// - simHash: SimHash of the text of <body>, 0 if it has too few words
+// - status: HTTP status code, once crawled
+// - depth: number of links from the root url when first discovered
+// - lastModified: Last-Modified header, if any, once crawled
 type Page struct {
-	StatList  map[string]StatPage
-	OutLinks  map[string]*PageWithCard
-	PageURL   *url.URL
-	FailCount uint
+	StatList     map[string]StatPage
+	OutLinks     map[string]*PageWithCard
+	External     map[string]uint
+	PageURL      *url.URL
+	FailCount    uint
+	Title        string
+	Description  string
+	H1           string
+	H1Count      int
+	MissingAlt   int
+	Canonical    string
+	NoIndex      bool
+	Anchors      []string
+	Insecure     []InsecureRef
+	Headers      map[string]string
+	Cookies      []Cookie
+	Lang         string
+	WordCount    int
+	ContentHash  string
+	SimHash      uint64
+	Status       int
+	Depth        uint
+	LastModified time.Time
+}
+
+// Stats are the crawl statistics of a run.
+type Stats struct {
+	Success   uint64
+	Skipped   uint64
+	Failed    uint64
+	Cancelled uint64
+}
+
+// CrawlInfo describes a finished crawl,
+// GraphProcessors get it when asked to Finish.
+// RootURL is normalized the same as the urls of pages.
+// External has the checks of the links of pages to other
+// hosts by url, nil if they were not checked.
+type CrawlInfo struct {
+	RootURL   string
+	StartTime time.Time
+	EndTime   time.Time
+	Stats     Stats
+	External  map[string]LinkCheck
 }
 
 type stringPage struct {
@@ -57,9 +178,11 @@ type NodeMap struct {
 
 // GraphProcessor exposes graph processing interface for
 // pages crawled by crawler.
+// ProcessPage is called for every crawled page and Finish once
+// after the crawl to write the output, both from the same goroutine.
 type GraphProcessor interface {
-	ProcessLoop(context.Context, chan *Page)
-	Result() chan string
+	ProcessPage(*Page) error
+	Finish(*CrawlInfo) error
 }
 
 // NodeMapper implements the lockless map interface for use by crawler.
@@ -67,3 +190,10 @@ type NodeMapper interface {
 	Exists(string) *Page
 	Add(string, *Page) error
 }
+
+// Spiller is implemented by NodeMappers which can move a
+// processed page out of memory and load it back, see BoltMap.
+type Spiller interface {
+	Spill(string) error
+	Load(string) (*Page, error)
+}